@@ -1,82 +1,539 @@
 package architecture
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	"net/url"
+	"net"
+	"strings"
 
+	"github.com/mittz/roleplay-webapp-assess/architecture/cache"
+	"github.com/mittz/roleplay-webapp-assess/architecture/cache/memorystore"
 	"github.com/mittz/roleplay-webapp-assess/architecture/computing"
+	"github.com/mittz/roleplay-webapp-assess/architecture/computing/appengine"
 	"github.com/mittz/roleplay-webapp-assess/architecture/computing/cloudrun"
 	"github.com/mittz/roleplay-webapp-assess/architecture/computing/computeengine"
 	"github.com/mittz/roleplay-webapp-assess/architecture/database"
 	"github.com/mittz/roleplay-webapp-assess/architecture/database/alloydb"
+	"github.com/mittz/roleplay-webapp-assess/architecture/database/bigtable"
 	"github.com/mittz/roleplay-webapp-assess/architecture/database/cloudspanner"
 	"github.com/mittz/roleplay-webapp-assess/architecture/database/cloudsql"
+	"github.com/mittz/roleplay-webapp-assess/architecture/database/firestore"
+	"github.com/mittz/roleplay-webapp-assess/architecture/domain"
+	"github.com/mittz/roleplay-webapp-assess/architecture/iam"
+	"github.com/mittz/roleplay-webapp-assess/architecture/inventory"
 	"github.com/mittz/roleplay-webapp-assess/architecture/loadbalancing"
+	"github.com/mittz/roleplay-webapp-assess/architecture/storage"
+	"github.com/mittz/roleplay-webapp-assess/architecture/storage/cloudstorage"
+	"golang.org/x/sync/errgroup"
 )
 
 type Architecture struct {
-	lb   loadbalancing.LoadBalancingHTTPS
-	apps []computing.Computing
-	db   database.Database
+	endpoint   string
+	lb         loadbalancing.LoadBalancingHTTPS
+	apps       []computing.Computing
+	db         database.Database
+	ignoredDBs []database.Database
+	caches     []cache.Cache
+	// Buckets which serve the images directly, not through the load balancer
+	imageBuckets []storage.Storage
+	resolution   []string
+	projectIDs   []string
+	// Projects owning the resources by their IDs
+	owners       map[string]string
+	projectRoles map[string]iam.ProjectRoles
 }
 
-func NewArchitecture(projectID string, endpoint string) (Architecture, error) {
-	arch := Architecture{}
+func NewArchitecture(ctx context.Context, projectID string, relatedProjectIDs []string, endpoint string) (Architecture, error) {
+	return NewArchitectureWithResolver(ctx, projectID, relatedProjectIDs, endpoint, DefaultResolver)
+}
+
+// NewArchitectureWithResolver discovers the architecture in the project and the related projects,
+// e.g. a data project or the Shared VPC host project which owns the load balancer
+func NewArchitectureWithResolver(ctx context.Context, projectID string, relatedProjectIDs []string, endpoint string, resolver Resolver) (Architecture, error) {
+	arch := Architecture{
+		endpoint:     endpoint,
+		owners:       make(map[string]string),
+		projectRoles: make(map[string]iam.ProjectRoles),
+	}
 
-	u, err := url.Parse(endpoint)
+	host, err := normalizeEndpoint(endpoint)
 	if err != nil {
 		return Architecture{}, err
 	}
-	host := u.Host
+	arch.resolution = append(arch.resolution, fmt.Sprintf("Endpoint: %s => Host: %s", endpoint, host))
+
+	arch.projectIDs = getProjectIDs(ctx, projectID, relatedProjectIDs)
+	if len(arch.projectIDs) > 1 {
+		arch.resolution = append(arch.resolution, fmt.Sprintf("Project: %s => Related projects: %s", projectID, strings.Join(arch.projectIDs[1:], ", ")))
+	}
+
+	// The resolvers query the snapshots of the projects instead of listing the resources one by one
+	inventory.Reset()
+	loadInventories(ctx, arch.projectIDs)
+
+	// Serverless products are still matched by the hostname even if it can't be resolved
+	addresses, err := resolveAddresses(ctx, resolver, host)
+	if err != nil {
+		arch.resolution = append(arch.resolution, fmt.Sprintf("Host: %s => Failed to resolve: %v", host, err))
+	} else if len(addresses) > 1 || addresses[0] != host {
+		arch.resolution = append(arch.resolution, fmt.Sprintf("Host: %s => Addresses: %s", host, strings.Join(addresses, ", ")))
+	}
 
-	if lb, ok := loadbalancing.GetLoadBalancingHTTPS(projectID, host); ok {
+	// Records in Cloud DNS and domain mappings connect a custom domain to the resources.
+	// A bare IP address has neither of them.
+	var mappings []domain.Mapping
+	if net.ParseIP(host) == nil {
+		for _, x := range arch.projectIDs {
+			mappings = append(mappings, domain.GetMappings(ctx, x, host)...)
+		}
+	}
+	for _, mapping := range mappings {
+		arch.resolution = append(arch.resolution, mapping.String())
+		if mapping.TargetType == domain.TARGET_ADDRESS && !contains(addresses, mapping.Target) {
+			addresses = append(addresses, mapping.Target)
+		}
+	}
+
+	if lb, address, owner, ok := getLoadBalancingHTTPS(ctx, arch.projectIDs, addresses); ok {
+		arch.resolution = append(arch.resolution, fmt.Sprintf("Address: %s => Load Balancing: %s (Project: %s)", address, lb.GetID(), owner))
 		arch.lb = lb
-		log.Printf("Load Balancing resource was found: %s", lb.GetID())
+		arch.owners[lb.GetID()] = owner
+		// The backends and the buckets can be in other projects, e.g. service projects of Shared VPC
+		for _, backend := range lb.GetBackends() {
+			arch.owners[backend.GetID()] = owner
+			if x, ok := lb.GetProjectID(backend.GetID()); ok && x != "" {
+				arch.owners[backend.GetID()] = x
+			}
+		}
+		for _, bucket := range lb.GetBuckets() {
+			arch.owners[bucket.GetID()] = owner
+			if bucket.GetProjectID() != "" {
+				arch.owners[bucket.GetID()] = bucket.GetProjectID()
+			}
+		}
+		if lb.IsRegional() {
+			log.Printf("Load Balancing resource was found: %s (Scheme: %s, Region: %s)", lb.GetID(), lb.GetScheme(), lb.GetRegion())
+		} else {
+			log.Printf("Load Balancing resource was found: %s (Scheme: %s, Global)", lb.GetID(), lb.GetScheme())
+		}
 
 		arch.apps = arch.lb.GetBackends()
+		for _, bucket := range arch.lb.GetBuckets() {
+			log.Printf("Cloud Storage resource was found: %s (Location: %s %s, Class: %s, Cloud CDN: %t)", bucket.GetID(), bucket.GetLocationType(), bucket.GetRegion(), bucket.GetStorageClass(), bucket.IsCDNEnabled())
+		}
+		for _, endpoint := range arch.lb.GetExternalEndpoints() {
+			if endpoint.Internet {
+				log.Printf("Internet NEG backend was found: %s (%s) - traffic leaves Google Cloud", endpoint.Address, endpoint.Group)
+			} else {
+				log.Printf("Hybrid NEG backend was found: %s (%s)", endpoint.Address, endpoint.Group)
+			}
+		}
 	} else {
-		if computing, ok := computeengine.GetComputeEngine(projectID, host); ok {
+		if computing, address, owner, ok := getComputeEngine(ctx, arch.projectIDs, addresses); ok {
+			arch.resolution = append(arch.resolution, fmt.Sprintf("Address: %s => Compute Engine: %s (Project: %s)", address, computing.GetID(), owner))
 			arch.apps = append(arch.apps, computing)
+			arch.owners[computing.GetID()] = owner
 			log.Printf("Compute Engine resource was found: %s", computing.GetID())
-		} else if computing, ok := cloudrun.GetCloudRun(projectID, host); ok {
+		} else if computing, owner, ok := getCloudRun(ctx, arch.projectIDs, host); ok {
+			arch.resolution = append(arch.resolution, fmt.Sprintf("Host: %s => Cloud Run: %s (Project: %s)", host, computing.GetID(), owner))
 			arch.apps = append(arch.apps, computing)
+			arch.owners[computing.GetID()] = owner
 			log.Printf("Cloud Run resource was found: %s", computing.GetID())
+		} else if computing, owner, ok := getMappedComputing(ctx, mappings); ok {
+			arch.apps = append(arch.apps, computing)
+			arch.owners[computing.GetID()] = owner
+			log.Printf("Computing resource mapped to %s was found: %s", host, computing.GetID())
+		} else if err := checkContext(ctx, "the computing resource"); err != nil {
+			return Architecture{}, err
 		} else {
 			return Architecture{}, fmt.Errorf("Computing resource (ProjectID: %s, Host: %s) was not found.", projectID, host)
 		}
 	}
 
-	dbCount := 0
-	if db, ok := cloudsql.GetCloudSQL(projectID); ok {
-		arch.db = db
-		dbCount++
-		log.Printf("Cloud SQL resource was found: %s", db.GetID())
+	// The apps can connect to Cloud SQL in a data project
+	for _, x := range getReferencedProjectIDs(arch.apps) {
+		if !contains(arch.projectIDs, x) {
+			arch.projectIDs = append(arch.projectIDs, x)
+			arch.resolution = append(arch.resolution, fmt.Sprintf("Cloud SQL connection name => Related project: %s", x))
+		}
+	}
+	loadInventories(ctx, arch.projectIDs)
+
+	for _, x := range arch.resolution {
+		log.Printf("Resolution: %s", x)
+	}
+
+	dbs, owners := listDatabases(ctx, arch.projectIDs)
+	if err := checkContext(ctx, "the database"); err != nil {
+		return Architecture{}, err
 	}
 
-	if db, ok := alloydb.GetAlloyDB(projectID); ok {
-		arch.db = db
-		dbCount++
-		log.Printf("AlloyDB resource was found: %s", db.GetID())
+	if len(dbs) == 0 {
+		return Architecture{}, fmt.Errorf("Database product was not found in %s", strings.Join(arch.projectIDs, ", "))
+	}
+	for id, owner := range owners {
+		arch.owners[id] = owner
 	}
 
-	if db, ok := cloudspanner.GetCloudSpanner(projectID); ok {
-		arch.db = db
-		dbCount++
-		log.Printf("Cloud Spanner resource was found: %s", db.GetID())
+	// Pick the database the apps are configured to use and ignore the others, e.g. leftovers
+	candidates := rankDatabases(dbs, arch.apps)
+	arch.db = candidates[0].db
+	if candidates[0].score == 0 {
+		log.Printf("Warning: None of the apps refers to any database, so the first one found is used: %s", arch.db.GetID())
+	}
+	log.Printf("Database resource in use: %s (Score: %d)", arch.db.GetID(), candidates[0].score)
+	for _, candidate := range candidates[1:] {
+		arch.ignoredDBs = append(arch.ignoredDBs, candidate.db)
+		log.Printf("Database resource was ignored: %s (Score: %d)", candidate.db.GetID(), candidate.score)
 	}
 
-	if dbCount == 0 {
-		return Architecture{}, fmt.Errorf("Database product was not found in %s", projectID)
+	caches, owners := getCaches(ctx, arch.projectIDs, arch.apps)
+	arch.caches = caches
+	for id, owner := range owners {
+		arch.owners[id] = owner
 	}
 
-	if dbCount > 1 {
-		return Architecture{}, fmt.Errorf("Multiple database products were detected in %s", projectID)
+	// The roles are used only by the lint rules, so the discovery goes on without them
+	for _, x := range arch.projectIDs {
+		if roles, err := iam.GetProjectRoles(ctx, x); err == nil {
+			arch.projectRoles[x] = roles
+		} else {
+			log.Printf("Failed to get the IAM policy of %s: %v", x, err)
+		}
 	}
 
 	return arch, nil
 }
 
+type databaseLister struct {
+	name string
+	list func(ctx context.Context, projectID string) ([]database.Database, error)
+}
+
+var databaseListers = []databaseLister{
+	{"Cloud SQL", func(ctx context.Context, projectID string) ([]database.Database, error) {
+		x, err := cloudsql.ListCloudSQL(ctx, projectID)
+		var dbs []database.Database
+		for _, db := range x {
+			dbs = append(dbs, db)
+		}
+		return dbs, err
+	}},
+	{"AlloyDB", func(ctx context.Context, projectID string) ([]database.Database, error) {
+		x, err := alloydb.ListAlloyDB(ctx, projectID)
+		if errors.Is(err, alloydb.ErrClusterNotFound) {
+			return nil, nil
+		}
+		var dbs []database.Database
+		for _, db := range x {
+			dbs = append(dbs, db)
+		}
+		return dbs, err
+	}},
+	{"Cloud Spanner", func(ctx context.Context, projectID string) ([]database.Database, error) {
+		x, err := cloudspanner.ListCloudSpanner(ctx, projectID)
+		var dbs []database.Database
+		for _, db := range x {
+			dbs = append(dbs, db)
+		}
+		return dbs, err
+	}},
+	{"Firestore", func(ctx context.Context, projectID string) ([]database.Database, error) {
+		x, err := firestore.ListFirestore(ctx, projectID)
+		var dbs []database.Database
+		for _, db := range x {
+			dbs = append(dbs, db)
+		}
+		return dbs, err
+	}},
+	{"Bigtable", func(ctx context.Context, projectID string) ([]database.Database, error) {
+		x, err := bigtable.ListBigtable(ctx, projectID)
+		var dbs []database.Database
+		for _, db := range x {
+			dbs = append(dbs, db)
+		}
+		return dbs, err
+	}},
+}
+
+// listDatabases lists the database products in the projects concurrently and returns their owners.
+// The order of the projects and the products is kept since the ranking of the databases depends on it.
+func listDatabases(ctx context.Context, projectIDs []string) ([]database.Database, map[string]string) {
+	results := make([][]database.Database, len(projectIDs)*len(databaseListers))
+	var g errgroup.Group
+	g.SetLimit(loadbalancing.DISCOVERY_PARALLELISM)
+	for i, projectID := range projectIDs {
+		for j, lister := range databaseListers {
+			index, projectID, lister := i*len(databaseListers)+j, projectID, lister
+			g.Go(func() error {
+				x, err := lister.list(ctx, projectID)
+				if err != nil {
+					log.Printf("listDatabases - %s (%s): %v", lister.name, projectID, err)
+					return nil
+				}
+				results[index] = x
+				return nil
+			})
+		}
+	}
+	g.Wait()
+
+	var dbs []database.Database
+	owners := make(map[string]string)
+	for i, projectID := range projectIDs {
+		for j, lister := range databaseListers {
+			for _, db := range results[i*len(databaseListers)+j] {
+				dbs = append(dbs, db)
+				owners[db.GetID()] = projectID
+				log.Printf("%s resource was found: %s (Project: %s)", lister.name, db.GetID(), projectID)
+			}
+		}
+	}
+
+	return dbs, owners
+}
+
+// getCaches returns the Memorystore instances in the projects which the apps refer to, and their owners.
+// The others are ignored in the same way as the databases.
+func getCaches(ctx context.Context, projectIDs []string, apps []computing.Computing) ([]cache.Cache, map[string]string) {
+	redis := make([][]memorystore.Redis, len(projectIDs))
+	memcache := make([][]memorystore.Memcache, len(projectIDs))
+	var g errgroup.Group
+	g.SetLimit(loadbalancing.DISCOVERY_PARALLELISM)
+	for i, projectID := range projectIDs {
+		i, projectID := i, projectID
+		g.Go(func() error {
+			x, err := memorystore.ListRedis(ctx, projectID)
+			if err != nil {
+				log.Printf("getCaches - memorystore.ListRedis (%s): %v", projectID, err)
+			}
+			redis[i] = x
+			return nil
+		})
+		g.Go(func() error {
+			x, err := memorystore.ListMemcache(ctx, projectID)
+			if err != nil {
+				log.Printf("getCaches - memorystore.ListMemcache (%s): %v", projectID, err)
+			}
+			memcache[i] = x
+			return nil
+		})
+	}
+	g.Wait()
+
+	var caches []cache.Cache
+	owners := make(map[string]string)
+	for i, projectID := range projectIDs {
+		for _, c := range redis[i] {
+			caches = append(caches, c)
+			owners[c.GetID()] = projectID
+		}
+		for _, c := range memcache[i] {
+			caches = append(caches, c)
+			owners[c.GetID()] = projectID
+		}
+	}
+
+	appReferences := getAppReferences(apps)
+
+	var x []cache.Cache
+	for _, c := range caches {
+		// Sharing the VPC network with the apps isn't enough as the caches are optional
+		score := scoreReferences(c.GetReferences(), appReferences)
+		if !hasDirectReference(c.GetReferences(), appReferences) {
+			log.Printf("Memorystore resource was ignored: %s (Tier: %s)", c.GetID(), c.GetTier())
+			delete(owners, c.GetID())
+			continue
+		}
+
+		x = append(x, c)
+		log.Printf("Memorystore resource was found: %s (Tier: %s, Score: %d, Project: %s)", c.GetID(), c.GetTier(), score, owners[c.GetID()])
+	}
+
+	return x, owners
+}
+
+// getLoadBalancingHTTPS tries every resolved address in every project and returns the first match with its project
+func getLoadBalancingHTTPS(ctx context.Context, projectIDs []string, addresses []string) (loadbalancing.LoadBalancingHTTPS, string, string, bool) {
+	for _, projectID := range projectIDs {
+		for _, address := range addresses {
+			if lb, ok := loadbalancing.GetLoadBalancingHTTPS(ctx, projectID, address); ok {
+				return lb, address, projectID, true
+			}
+		}
+	}
+
+	return loadbalancing.LoadBalancingHTTPS{}, "", "", false
+}
+
+func getComputeEngine(ctx context.Context, projectIDs []string, addresses []string) (computeengine.ComputeEngine, string, string, bool) {
+	for _, projectID := range projectIDs {
+		for _, address := range addresses {
+			if computing, ok := computeengine.GetComputeEngine(ctx, projectID, address); ok {
+				return computing, address, projectID, true
+			}
+		}
+	}
+
+	return computeengine.ComputeEngine{}, "", "", false
+}
+
+func getCloudRun(ctx context.Context, projectIDs []string, host string) (cloudrun.CloudRun, string, bool) {
+	// The URLs of Cloud Run services are hostnames, so a bare IP address never matches
+	if net.ParseIP(host) != nil {
+		return cloudrun.CloudRun{}, "", false
+	}
+
+	for _, projectID := range projectIDs {
+		if computing, ok := cloudrun.GetCloudRun(ctx, projectID, host); ok {
+			return computing, projectID, true
+		}
+	}
+
+	return cloudrun.CloudRun{}, "", false
+}
+
+// getMappedComputing returns the serverless service which a domain mapping routes to, and its project
+func getMappedComputing(ctx context.Context, mappings []domain.Mapping) (computing.Computing, string, bool) {
+	for _, mapping := range mappings {
+		switch mapping.TargetType {
+		case domain.TARGET_CLOUD_RUN:
+			x, err := cloudrun.GetCloudRunService(ctx, mapping.ProjectID, mapping.Region, mapping.Target)
+			if err != nil {
+				log.Printf("getMappedComputing - cloudrun.GetCloudRunService: %v", err)
+				continue
+			}
+			if x.GetID() == "" {
+				continue
+			}
+			return x, mapping.ProjectID, true
+		case domain.TARGET_APP_ENGINE:
+			x, err := appengine.GetAppEngineApplication(ctx, mapping.ProjectID)
+			if err != nil {
+				log.Printf("getMappedComputing - appengine.GetAppEngineApplication: %v", err)
+				continue
+			}
+			if x.GetID() == "" {
+				continue
+			}
+			return x, mapping.ProjectID, true
+		}
+	}
+
+	return nil, "", false
+}
+
+func contains(values []string, value string) bool {
+	for _, x := range values {
+		if x == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AddImageBuckets attributes the buckets which serve the image URLs seen during the benchmark.
+// The backend buckets of the load balancer are already a part of the architecture.
+func (a *Architecture) AddImageBuckets(ctx context.Context, imageURLs []string) {
+	seen := make(map[string]interface{})
+	for _, bucket := range a.lb.GetBuckets() {
+		seen[bucket.GetID()] = struct{}{}
+	}
+	for _, bucket := range a.imageBuckets {
+		seen[bucket.GetID()] = struct{}{}
+	}
+
+	for _, imageURL := range imageURLs {
+		bucketName, ok := cloudstorage.GetBucketName(imageURL)
+		if !ok {
+			continue
+		}
+		if _, ok := seen[bucketName]; ok {
+			continue
+		}
+		seen[bucketName] = struct{}{}
+
+		bucket, err := cloudstorage.GetCloudStorage(ctx, bucketName, false)
+		if err != nil {
+			log.Printf("AddImageBuckets - cloudstorage.GetCloudStorage: %v", err)
+			continue
+		}
+
+		a.imageBuckets = append(a.imageBuckets, bucket)
+		if bucket.GetProjectID() != "" {
+			a.owners[bucket.GetID()] = bucket.GetProjectID()
+		}
+		a.resolution = append(a.resolution, fmt.Sprintf("Image: %s => Cloud Storage: %s (Project: %s)", imageURL, bucket.GetID(), bucket.GetProjectID()))
+		log.Printf("Cloud Storage resource was found: %s (Location: %s %s, Class: %s)", bucket.GetID(), bucket.GetLocationType(), bucket.GetRegion(), bucket.GetStorageClass())
+	}
+}
+
+// GetBuckets returns the buckets behind the load balancer and the ones serving the images directly
+func (a Architecture) GetBuckets() []storage.Storage {
+	return append(append([]storage.Storage{}, a.lb.GetBuckets()...), a.imageBuckets...)
+}
+
+// GetCaches returns the cache tier which the apps use in front of the database
+func (a Architecture) GetCaches() []cache.Cache {
+	return a.caches
+}
+
+// GetIgnoredDatabases returns the databases which were found but aren't used by the apps
+func (a Architecture) GetIgnoredDatabases() []database.Database {
+	return a.ignoredDBs
+}
+
+func (a Architecture) GetLoadBalancing() loadbalancing.LoadBalancingHTTPS {
+	return a.lb
+}
+
+func (a Architecture) GetApps() []computing.Computing {
+	return a.apps
+}
+
+func (a Architecture) GetDatabase() database.Database {
+	return a.db
+}
+
+// GetProjectIDs returns the project and the related projects which were searched
+func (a Architecture) GetProjectIDs() []string {
+	return a.projectIDs
+}
+
+// GetProjectID returns the project owning the resource
+func (a Architecture) GetProjectID(resourceID string) (string, bool) {
+	projectID, ok := a.owners[resourceID]
+	return projectID, ok
+}
+
+func (a Architecture) GetProjectRoles(projectID string) (iam.ProjectRoles, bool) {
+	roles, ok := a.projectRoles[projectID]
+	return roles, ok
+}
+
+// GetResolution returns how the endpoint was resolved into the resources, e.g.
+// Host: shop.example.com => Addresses: 203.0.113.10
+// Address: 203.0.113.10 => Load Balancing: shop-forwarding-rule
+func (a Architecture) GetResolution() []string {
+	return a.resolution
+}
+
+// getServingApps returns the apps except the unhealthy instances behind the load balancer,
+// since they don't serve any request in a zone outage either
+func (a Architecture) getServingApps() []computing.Computing {
+	var apps []computing.Computing
+	for _, app := range a.apps {
+		if a.lb.IsHealthy(app) {
+			apps = append(apps, app)
+		}
+	}
+
+	return apps
+}
+
 func (a Architecture) CalcAvailabilityRate() ([]int, error) {
 	if len(a.apps) == 0 {
 		return nil, fmt.Errorf("Computing product was not found")
@@ -86,7 +543,7 @@ func (a Architecture) CalcAvailabilityRate() ([]int, error) {
 	appRegions, appZones := make(map[string]interface{}), make(map[string]interface{})
 
 	includeServerless := false
-	for _, app := range a.apps {
+	for _, app := range a.getServingApps() {
 		appRegions[app.GetRegion()] = struct{}{}
 		switch app.(type) {
 		case computeengine.ComputeEngine:
@@ -109,11 +566,29 @@ func (a Architecture) CalcAvailabilityRate() ([]int, error) {
 		appRate = 0
 	}
 
+	// A regional load balancer is a single point of failure of its region
+	// even if the backends are spread over multiple regions
+	if a.lb.IsRegional() && appRate > 2 {
+		appRate = 2
+	}
+
 	if a.db == nil {
 		return nil, fmt.Errorf("Database product was not found")
 	}
 	dbRate = a.db.GetAvailabilityRate()
 
+	// The cache tier is on the path of the requests, so the weakest cache is a component as well
+	if len(a.caches) > 0 {
+		cacheRate := a.caches[0].GetAvailabilityRate()
+		for _, c := range a.caches[1:] {
+			if c.GetAvailabilityRate() < cacheRate {
+				cacheRate = c.GetAvailabilityRate()
+			}
+		}
+
+		return []int{appRate, dbRate, cacheRate}, nil
+	}
+
 	return []int{appRate, dbRate}, nil
 }
 
@@ -124,9 +599,21 @@ func (a Architecture) CalcCost() float64 {
 		total += app.GetCost()
 	}
 
+	for _, bucket := range a.GetBuckets() {
+		total += bucket.GetCost()
+	}
+
 	if a.db != nil {
 		total += a.db.GetCost()
 	}
 
+	for _, c := range a.caches {
+		total += c.GetCost()
+	}
+
 	return total
 }
+
+func (a Architecture) IsCDNEnabled() bool {
+	return a.lb.IsCDNEnabled()
+}
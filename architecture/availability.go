@@ -0,0 +1,190 @@
+package architecture
+
+import (
+	"fmt"
+
+	"github.com/mittz/roleplay-webapp-assess/architecture/computing"
+	"github.com/mittz/roleplay-webapp-assess/architecture/computing/appengine"
+	"github.com/mittz/roleplay-webapp-assess/architecture/computing/cloudfunctions"
+	"github.com/mittz/roleplay-webapp-assess/architecture/computing/cloudrun"
+	"github.com/mittz/roleplay-webapp-assess/architecture/computing/computeengine"
+	"github.com/mittz/roleplay-webapp-assess/sla"
+)
+
+// Availability is the expected availability of the architecture and its tiers in percent.
+// The tiers without any resource are 0.
+type Availability struct {
+	Overall       float64 `json:"overall"`
+	LoadBalancing float64 `json:"loadBalancing,omitempty"`
+	App           float64 `json:"app"`
+	Storage       float64 `json:"storage,omitempty"`
+	Database      float64 `json:"database"`
+	Cache         float64 `json:"cache,omitempty"`
+	// Legacy availability rates of the app, the database and the cache if any
+	Rates []int `json:"rates"`
+}
+
+// CalcExpectedAvailability combines the SLAs of the components along the topology.
+// The tiers are in series since every request goes through them, while the backends
+// in multiple zones or regions are in parallel since any one of them can serve a request.
+func (a Architecture) CalcExpectedAvailability() (Availability, error) {
+	if len(a.apps) == 0 {
+		return Availability{}, fmt.Errorf("Computing product was not found")
+	}
+
+	if a.db == nil {
+		return Availability{}, fmt.Errorf("Database product was not found")
+	}
+
+	var x Availability
+	var appRate int
+	if a.lb.GetID() != "" {
+		x.LoadBalancing = sla.LOAD_BALANCING_SLA
+		x.App, appRate = a.calcRoutesAvailability()
+	} else {
+		x.App = calcComputingAvailability(a.apps)
+		appRate = sla.ToRate(x.App)
+	}
+
+	availabilities := []float64{x.App, a.db.GetSLA()}
+	if x.LoadBalancing > 0 {
+		availabilities = append(availabilities, x.LoadBalancing)
+	}
+
+	// Buckets serve the static contents, so they are required as well
+	var buckets []float64
+	for _, bucket := range a.GetBuckets() {
+		buckets = append(buckets, bucket.GetSLA())
+	}
+	if len(buckets) > 0 {
+		x.Storage = sla.Series(buckets...)
+		availabilities = append(availabilities, x.Storage)
+	}
+
+	x.Database = a.db.GetSLA()
+	x.Rates = []int{appRate, sla.ToRate(x.Database)}
+
+	if len(a.caches) > 0 {
+		// The rate is of the weakest cache like the legacy model, since the rates don't add up like the availabilities
+		var caches []float64
+		cacheRate := 0
+		for _, c := range a.caches {
+			caches = append(caches, c.GetSLA())
+			if r := sla.ToCacheRate(c.GetSLA()); cacheRate == 0 || r < cacheRate {
+				cacheRate = r
+			}
+		}
+		x.Cache = sla.Series(caches...)
+		x.Rates = append(x.Rates, cacheRate)
+		availabilities = append(availabilities, x.Cache)
+	}
+
+	x.Overall = sla.Series(availabilities...)
+
+	return x, nil
+}
+
+// calcRoutesAvailability returns the availability of the backend services of the load balancer in series
+// and the legacy rate of the weakest one, since the rates don't add up like the availabilities
+func (a Architecture) calcRoutesAvailability() (float64, int) {
+	backends := make(map[string]computing.Computing)
+	for _, app := range a.apps {
+		backends[app.GetID()] = app
+	}
+	serving := make(map[string]bool)
+	for _, app := range a.getServingApps() {
+		serving[app.GetID()] = true
+	}
+
+	var availabilities []float64
+	rate := 0
+	for _, route := range a.lb.GetRoutes() {
+		var apps []computing.Computing
+		for _, id := range route.Backends {
+			if app, ok := backends[id]; ok {
+				apps = append(apps, app)
+			}
+		}
+		if len(apps) == 0 {
+			continue
+		}
+
+		// The backend service is down when none of its backends is healthy
+		var servingApps []computing.Computing
+		for _, app := range apps {
+			if serving[app.GetID()] {
+				servingApps = append(servingApps, app)
+			}
+		}
+		availability := calcComputingAvailability(servingApps)
+		// A regional load balancer fails with its region even if the backends are spread over multiple regions
+		if a.lb.IsRegional() && availability > sla.REGION_SLA {
+			availability = sla.REGION_SLA
+		}
+
+		availabilities = append(availabilities, availability)
+		if r := sla.ToRate(availability); rate == 0 || r < rate {
+			rate = r
+		}
+	}
+
+	if len(availabilities) == 0 {
+		availability := calcComputingAvailability(a.getServingApps())
+		return availability, sla.ToRate(availability)
+	}
+
+	return sla.Series(availabilities...), rate
+}
+
+// calcComputingAvailability returns the availability of the apps which serve the same requests.
+// The apps in a region are in parallel but bounded by the region, and the regions are in parallel.
+func calcComputingAvailability(apps []computing.Computing) float64 {
+	zones := make(map[string]map[string]interface{})
+	serverlesses := make(map[string][]float64)
+	var regions []string
+	for _, app := range apps {
+		region := app.GetRegion()
+		if _, ok := zones[region]; !ok {
+			zones[region] = make(map[string]interface{})
+			regions = append(regions, region)
+		}
+
+		switch app.(type) {
+		case computeengine.ComputeEngine:
+			zones[region][app.GetZone()] = struct{}{}
+		default:
+			serverlesses[region] = append(serverlesses[region], getServerlessSLA(app))
+		}
+	}
+
+	var availabilities []float64
+	for _, region := range regions {
+		x := serverlesses[region]
+		if len(zones[region]) > 1 {
+			x = append(x, sla.COMPUTE_ENGINE_MULTI_ZONE_SLA)
+		} else if len(zones[region]) == 1 {
+			x = append(x, sla.COMPUTE_ENGINE_SINGLE_ZONE_SLA)
+		}
+
+		availability := sla.Parallel(x...)
+		if availability > sla.REGION_SLA {
+			availability = sla.REGION_SLA
+		}
+		availabilities = append(availabilities, availability)
+	}
+
+	return sla.Parallel(availabilities...)
+}
+
+func getServerlessSLA(app computing.Computing) float64 {
+	switch app.(type) {
+	case cloudrun.CloudRun:
+		return sla.CLOUD_RUN_SLA
+	case appengine.AppEngine:
+		return sla.APP_ENGINE_SLA
+	case cloudfunctions.CloudFunctions:
+		return sla.CLOUD_FUNCTIONS_SLA
+	default:
+		return sla.COMPUTE_ENGINE_SINGLE_ZONE_SLA
+	}
+}
@@ -0,0 +1,13 @@
+package cache
+
+type Cache interface {
+	GetID() string
+	GetAvailabilityRate() int
+	// GetSLA returns the expected availability in percent, e.g. 99.9
+	GetSLA() float64
+	GetCost() float64
+	SetCost(float64)
+	GetRegion() string
+	GetTier() string
+	GetReferences() []string
+}
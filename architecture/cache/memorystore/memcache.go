@@ -0,0 +1,154 @@
+package memorystore
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/mittz/roleplay-webapp-assess/cost"
+	"github.com/mittz/roleplay-webapp-assess/recorder"
+	"github.com/mittz/roleplay-webapp-assess/sla"
+	"google.golang.org/api/memcache/v1"
+)
+
+const (
+	MEMCACHE_TIER = "MEMCACHE"
+)
+
+type Memcache struct {
+	id               string
+	region           string
+	availabilityRate int
+	sla              float64
+	cost             float64
+	references       []string
+}
+
+type MemcacheInstance struct {
+	Name         string
+	NodeCount    int64
+	CPUCount     int64
+	MemorySizeMB int64
+	Zones        []string
+	Hosts        []string
+	Network      string
+}
+
+func getMemcacheInstances(ctx context.Context, projectID string) ([]MemcacheInstance, error) {
+	service, err := memcache.NewService(ctx, recorder.HTTPClientOptions()...)
+	if err != nil {
+		return []MemcacheInstance{}, err
+	}
+
+	var instances []MemcacheInstance
+	parent := fmt.Sprintf("projects/%s/locations/-", projectID)
+	if err := service.Projects.Locations.Instances.List(parent).Pages(ctx, func(resp *memcache.ListInstancesResponse) error {
+		for _, instance := range resp.Instances {
+			x := MemcacheInstance{
+				Name:      instance.Name,
+				NodeCount: instance.NodeCount,
+				Zones:     instance.Zones,
+				Network:   instance.AuthorizedNetwork,
+			}
+			if instance.NodeConfig != nil {
+				x.CPUCount = instance.NodeConfig.CpuCount
+				x.MemorySizeMB = instance.NodeConfig.MemorySizeMb
+			}
+			if instance.DiscoveryEndpoint != "" {
+				x.Hosts = append(x.Hosts, instance.DiscoveryEndpoint)
+			}
+
+			// The nodes are spread over the zones of the region unless the zones are specified
+			zones := make(map[string]interface{})
+			for _, node := range instance.MemcacheNodes {
+				zones[node.Zone] = struct{}{}
+				if node.Host != "" {
+					x.Hosts = append(x.Hosts, node.Host)
+				}
+			}
+			if len(x.Zones) == 0 {
+				for zone := range zones {
+					x.Zones = append(x.Zones, zone)
+				}
+			}
+
+			instances = append(instances, x)
+		}
+		return nil
+	}); err != nil {
+		return []MemcacheInstance{}, err
+	}
+
+	return instances, nil
+}
+
+// ListMemcache returns every Memorystore for Memcached instance in the project
+func ListMemcache(ctx context.Context, projectID string) ([]Memcache, error) {
+	instances, err := getMemcacheInstances(ctx, projectID)
+	if err != nil {
+		return []Memcache{}, err
+	}
+
+	var x []Memcache
+	for _, instance := range instances {
+		x = append(x, newMemcache(instance))
+	}
+
+	return x, nil
+}
+
+func newMemcache(instance MemcacheInstance) Memcache {
+	// Memcached doesn't replicate the data, but the nodes in the other zones keep serving in a zone outage
+	availabilityRate := 1
+	availability := sla.ZONAL_ASSUMED_SLA
+	if len(instance.Zones) > 1 && instance.NodeCount > 1 {
+		availabilityRate = 2
+		availability = sla.MEMORYSTORE_MEMCACHE_SLA
+	}
+
+	references := append([]string{instance.Name, path.Base(instance.Name)}, instance.Hosts...)
+	if instance.Network != "" {
+		references = append(references, fmt.Sprintf("network:%s", path.Base(instance.Network)))
+	}
+
+	return Memcache{
+		id:               instance.Name,
+		region:           getRegion(instance.Name),
+		availabilityRate: availabilityRate,
+		sla:              availability,
+		cost:             float64(instance.NodeCount) * (float64(instance.CPUCount)*cost.MEMORYSTORE_MEMCACHE_COST_PER_CPU + float64(instance.MemorySizeMB)*cost.MEMORYSTORE_MEMCACHE_COST_PER_MEM_MB),
+		references:       references,
+	}
+}
+
+func (r Memcache) GetID() string {
+	return r.id
+}
+
+func (r Memcache) GetSLA() float64 {
+	return r.sla
+}
+
+func (r Memcache) GetAvailabilityRate() int {
+	return r.availabilityRate
+}
+
+func (r Memcache) GetCost() float64 {
+	return r.cost
+}
+
+func (r Memcache) SetCost(cost float64) {
+	r.cost = cost
+}
+
+func (r Memcache) GetRegion() string {
+	return r.region
+}
+
+func (r Memcache) GetTier() string {
+	return MEMCACHE_TIER
+}
+
+func (r Memcache) GetReferences() []string {
+	return r.references
+}
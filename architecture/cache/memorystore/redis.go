@@ -0,0 +1,167 @@
+package memorystore
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/mittz/roleplay-webapp-assess/cost"
+	"github.com/mittz/roleplay-webapp-assess/recorder"
+	"github.com/mittz/roleplay-webapp-assess/sla"
+	"google.golang.org/api/redis/v1"
+)
+
+const (
+	REDIS_TIER_BASIC       = "BASIC"
+	REDIS_TIER_STANDARD_HA = "STANDARD_HA"
+)
+
+type Redis struct {
+	id               string
+	region           string
+	tier             string
+	availabilityRate int
+	sla              float64
+	cost             float64
+	references       []string
+}
+
+type RedisInstance struct {
+	Name                  string
+	Tier                  string
+	MemorySizeGiB         int64
+	ReplicaCount          int64
+	LocationID            string
+	AlternativeLocationID string
+	Host                  string
+	Network               string
+}
+
+func getRedisInstances(ctx context.Context, projectID string) ([]RedisInstance, error) {
+	service, err := redis.NewService(ctx, recorder.HTTPClientOptions()...)
+	if err != nil {
+		return []RedisInstance{}, err
+	}
+
+	var instances []RedisInstance
+	parent := fmt.Sprintf("projects/%s/locations/-", projectID)
+	if err := service.Projects.Locations.Instances.List(parent).Pages(ctx, func(resp *redis.ListInstancesResponse) error {
+		for _, instance := range resp.Instances {
+			instances = append(instances, RedisInstance{
+				Name:                  instance.Name,
+				Tier:                  instance.Tier,
+				MemorySizeGiB:         instance.MemorySizeGb,
+				ReplicaCount:          instance.ReplicaCount,
+				LocationID:            instance.LocationId,
+				AlternativeLocationID: instance.AlternativeLocationId,
+				Host:                  instance.Host,
+				Network:               instance.AuthorizedNetwork,
+			})
+		}
+		return nil
+	}); err != nil {
+		return []RedisInstance{}, err
+	}
+
+	return instances, nil
+}
+
+// "projects/<projectID>/locations/<region>/instances/<instanceName>" => <region>
+func getRegion(name string) string {
+	names := strings.Split(name, "/")
+	if len(names) < 4 {
+		return ""
+	}
+
+	return names[3]
+}
+
+// GetNodeCount returns the number of nodes holding the whole dataset.
+// A Standard Tier instance has at least one replica in addition to the primary.
+func (i RedisInstance) GetNodeCount() int64 {
+	if i.Tier != REDIS_TIER_STANDARD_HA {
+		return 1
+	}
+
+	if i.ReplicaCount < 1 {
+		return 2
+	}
+
+	return i.ReplicaCount + 1
+}
+
+// ListRedis returns every Memorystore for Redis instance in the project
+func ListRedis(ctx context.Context, projectID string) ([]Redis, error) {
+	instances, err := getRedisInstances(ctx, projectID)
+	if err != nil {
+		return []Redis{}, err
+	}
+
+	var x []Redis
+	for _, instance := range instances {
+		x = append(x, newRedis(instance))
+	}
+
+	return x, nil
+}
+
+func newRedis(instance RedisInstance) Redis {
+	// A Standard Tier instance fails over to the replica in the alternative zone
+	availabilityRate := 1
+	availability := sla.ZONAL_ASSUMED_SLA
+	if instance.Tier == REDIS_TIER_STANDARD_HA {
+		availabilityRate = 2
+		availability = sla.MEMORYSTORE_REDIS_STANDARD_SLA
+	}
+
+	references := []string{instance.Name, path.Base(instance.Name)}
+	if instance.Host != "" {
+		references = append(references, instance.Host)
+	}
+	if instance.Network != "" {
+		references = append(references, fmt.Sprintf("network:%s", path.Base(instance.Network)))
+	}
+
+	return Redis{
+		id:               instance.Name,
+		region:           getRegion(instance.Name),
+		tier:             instance.Tier,
+		availabilityRate: availabilityRate,
+		sla:              availability,
+		cost:             float64(instance.MemorySizeGiB*instance.GetNodeCount()) * cost.MEMORYSTORE_REDIS_COST_PER_GIB,
+		references:       references,
+	}
+}
+
+func (r Redis) GetID() string {
+	return r.id
+}
+
+func (r Redis) GetSLA() float64 {
+	return r.sla
+}
+
+func (r Redis) GetAvailabilityRate() int {
+	return r.availabilityRate
+}
+
+func (r Redis) GetCost() float64 {
+	return r.cost
+}
+
+func (r Redis) SetCost(cost float64) {
+	r.cost = cost
+}
+
+func (r Redis) GetRegion() string {
+	return r.region
+}
+
+func (r Redis) GetTier() string {
+	return r.tier
+}
+
+func (r Redis) GetReferences() []string {
+	return r.references
+}
@@ -3,17 +3,21 @@ package appengine
 import (
 	"context"
 	"fmt"
+	"path"
+	"strings"
 
 	appengine "cloud.google.com/go/appengine/apiv1"
 	"github.com/mittz/roleplay-webapp-assess/cost"
+	"github.com/mittz/roleplay-webapp-assess/recorder"
 	"google.golang.org/api/iterator"
 	appenginepb "google.golang.org/genproto/googleapis/appengine/v1"
 )
 
 type AppEngine struct {
-	id     string
-	region string
-	cost   float64
+	id         string
+	region     string
+	cost       float64
+	references []string
 }
 
 type Application struct {
@@ -23,12 +27,15 @@ type Application struct {
 
 type Service struct {
 	name string
+	id   string
 }
 
 type Version struct {
 	name             string
+	id               string
 	instanceClass    string
 	avgInstanceCount int
+	references       []string
 }
 
 // https://cloud.google.com/appengine/docs/standard#instance_classes
@@ -44,12 +51,24 @@ var costTables = map[string]float64{
 	"B8":    cost.SERVERLESS_COST_PER_CPU_CORE*4.8 + cost.SERVERLESS_COST_PER_MEM_MIB*2048, // CPU: 4.8 GHz Mem: 2048 MB
 }
 
-func getApplication(projectID string, hostName string) (Application, error) {
-	ctx := context.Background()
-	c, err := appengine.NewApplicationsClient(ctx)
+func getApplication(ctx context.Context, projectID string, hostName string) (Application, error) {
+	application, hostname, err := getProjectApplication(ctx, projectID)
 	if err != nil {
 		return Application{}, err
 	}
+
+	if hostname != hostName {
+		return Application{}, fmt.Errorf("App Engine Application was not found.")
+	}
+
+	return application, nil
+}
+
+func getProjectApplication(ctx context.Context, projectID string) (Application, string, error) {
+	c, err := appengine.NewApplicationsClient(ctx, recorder.GRPCClientOptions()...)
+	if err != nil {
+		return Application{}, "", err
+	}
 	defer c.Close()
 
 	req := &appenginepb.GetApplicationRequest{
@@ -57,11 +76,7 @@ func getApplication(projectID string, hostName string) (Application, error) {
 	}
 	resp, err := c.GetApplication(ctx, req)
 	if err != nil {
-		return Application{}, err
-	}
-
-	if resp.GetDefaultHostname() != hostName {
-		return Application{}, fmt.Errorf("App Engine Application was not found.")
+		return Application{}, "", err
 	}
 
 	var location string
@@ -77,12 +92,11 @@ func getApplication(projectID string, hostName string) (Application, error) {
 	return Application{
 		name:   resp.GetName(),
 		region: location,
-	}, nil
+	}, resp.GetDefaultHostname(), nil
 }
 
-func (a Application) GetServices() ([]Service, error) {
-	ctx := context.Background()
-	c, err := appengine.NewServicesClient(ctx)
+func (a Application) GetServices(ctx context.Context) ([]Service, error) {
+	c, err := appengine.NewServicesClient(ctx, recorder.GRPCClientOptions()...)
 	if err != nil {
 		return []Service{}, err
 	}
@@ -103,15 +117,14 @@ func (a Application) GetServices() ([]Service, error) {
 			return []Service{}, err
 		}
 
-		services = append(services, Service{name: resp.GetName()})
+		services = append(services, Service{name: resp.GetName(), id: resp.GetId()})
 	}
 
 	return services, nil
 }
 
-func (s Service) GetVersions() ([]Version, error) {
-	ctx := context.Background()
-	c, err := appengine.NewVersionsClient(ctx)
+func (s Service) GetVersions(ctx context.Context) ([]Version, error) {
+	c, err := appengine.NewVersionsClient(ctx, recorder.GRPCClientOptions()...)
 	if err != nil {
 		return []Version{}, err
 	}
@@ -119,6 +132,7 @@ func (s Service) GetVersions() ([]Version, error) {
 
 	req := &appenginepb.ListVersionsRequest{
 		Parent: s.name,
+		View:   appenginepb.VersionView_FULL, // To include beta_settings and env_variables
 	}
 
 	var versions []Version
@@ -140,45 +154,160 @@ func (s Service) GetVersions() ([]Version, error) {
 
 		versions = append(versions, Version{
 			name:             resp.GetName(),
+			id:               resp.GetId(),
 			instanceClass:    resp.GetInstanceClass(),
 			avgInstanceCount: (int(minInstanceCount) + int(maxInstanceCount)) / 2,
+			references:       getDatabaseReferences(resp),
 		})
 	}
 
 	return versions, nil
 }
 
-func GetAppEngine(projectID string, hostName string) (AppEngine, bool) {
-	application, err := getApplication(projectID, hostName)
+func GetAppEngine(ctx context.Context, projectID string, hostName string) (AppEngine, bool) {
+	application, err := getApplication(ctx, projectID, hostName)
 	if err != nil {
 		return AppEngine{}, false
 	}
 
-	services, err := application.GetServices()
+	x, err := application.toAppEngine(ctx)
 	if err != nil {
 		return AppEngine{}, false
 	}
 
+	return x, true
+}
+
+// GetAppEngineApplication returns the App Engine application of the project regardless of its hostname,
+// e.g. when it is served through a custom domain
+func GetAppEngineApplication(ctx context.Context, projectID string) (AppEngine, error) {
+	application, _, err := getProjectApplication(ctx, projectID)
+	if err != nil {
+		return AppEngine{}, err
+	}
+
+	return application.toAppEngine(ctx)
+}
+
+func (a Application) toAppEngine(ctx context.Context) (AppEngine, error) {
+	services, err := a.GetServices(ctx)
+	if err != nil {
+		return AppEngine{}, err
+	}
+
 	var versions []Version
 	for _, service := range services {
-		vs, err := service.GetVersions()
+		vs, err := service.GetVersions(ctx)
 		if err != nil {
-			return AppEngine{}, false
+			return AppEngine{}, err
 		}
 
 		versions = append(versions, vs...)
 	}
 
+	return AppEngine{
+		id:         a.name,
+		region:     a.region,
+		cost:       calcCost(versions),
+		references: collectReferences(versions),
+	}, nil
+}
+
+// getDatabaseReferences returns the Cloud SQL connections, environment variables and network of the version
+func getDatabaseReferences(version *appenginepb.Version) []string {
+	var references []string
+	// e.g. cloud_sql_instances: <projectID>:<region>:<instance>=tcp:5432,...
+	for _, x := range strings.Split(version.GetBetaSettings()["cloud_sql_instances"], ",") {
+		if x = strings.TrimSpace(strings.Split(x, "=")[0]); x != "" {
+			references = append(references, x)
+		}
+	}
+
+	for _, value := range version.GetEnvVariables() {
+		if value != "" {
+			references = append(references, value)
+		}
+	}
+
+	if network := version.GetNetwork().GetName(); network != "" {
+		references = append(references, fmt.Sprintf("network:%s", path.Base(network)))
+	}
+
+	return references
+}
+
+func calcCost(versions []Version) float64 {
 	var cost float64
 	for _, version := range versions {
 		cost += costTables[version.instanceClass] * float64(version.avgInstanceCount)
 	}
 
+	return cost
+}
+
+func collectReferences(versions []Version) []string {
+	var references []string
+	for _, version := range versions {
+		references = append(references, version.references...)
+	}
+
+	return references
+}
+
+// ListServiceIDs returns every service ID of the App Engine application, e.g. default
+func ListServiceIDs(ctx context.Context, projectID string) ([]string, error) {
+	application, _, err := getProjectApplication(ctx, projectID)
+	if err != nil {
+		return []string{}, err
+	}
+
+	services, err := application.GetServices(ctx)
+	if err != nil {
+		return []string{}, err
+	}
+
+	var ids []string
+	for _, service := range services {
+		ids = append(ids, service.id)
+	}
+
+	return ids, nil
+}
+
+// GetAppEngineService returns a single service of the App Engine application.
+// All versions of the service are counted when versionID is empty.
+func GetAppEngineService(ctx context.Context, projectID string, serviceID string, versionID string) (AppEngine, error) {
+	application, _, err := getProjectApplication(ctx, projectID)
+	if err != nil {
+		return AppEngine{}, err
+	}
+
+	service := Service{
+		name: fmt.Sprintf("%s/services/%s", application.name, serviceID),
+		id:   serviceID,
+	}
+	versions, err := service.GetVersions(ctx)
+	if err != nil {
+		return AppEngine{}, err
+	}
+
+	var targets []Version
+	for _, version := range versions {
+		if versionID == "" || version.id == versionID {
+			targets = append(targets, version)
+		}
+	}
+
+	if len(targets) == 0 {
+		return AppEngine{}, fmt.Errorf("App Engine Service: %s (Version: %s) doesn't exist in %s", serviceID, versionID, projectID)
+	}
+
 	return AppEngine{
-		id:     application.name,
-		region: application.region,
-		cost:   cost,
-	}, true
+		id:         service.name,
+		region:     application.region,
+		cost:       calcCost(targets),
+		references: collectReferences(targets),
+	}, nil
 }
 
 func (r AppEngine) GetID() string {
@@ -194,9 +323,13 @@ func (r AppEngine) GetCost() float64 {
 }
 
 func (r AppEngine) GetRegion() string {
-	return ""
+	return r.region
 }
 
 func (r AppEngine) GetZone() string {
 	return ""
 }
+
+func (r AppEngine) GetDatabaseReferences() []string {
+	return r.references
+}
@@ -6,30 +6,60 @@ import (
 	"log"
 	"net/url"
 	"path"
-	"strconv"
 	"strings"
 
 	functions "cloud.google.com/go/functions/apiv2"
 	"github.com/mittz/roleplay-webapp-assess/cost"
+	"github.com/mittz/roleplay-webapp-assess/recorder"
+	"github.com/mittz/roleplay-webapp-assess/utils"
 	"google.golang.org/api/iterator"
 	functionspb "google.golang.org/genproto/googleapis/cloud/functions/v2"
 )
 
-var availableCPUs = map[string]float64{
-	"128M": 0.083,
-	"256M": 0.167,
-	"512M": 0.333,
-	"1G":   0.583,
-	"2G":   1,
-	"4G":   2,
-	"8G":   2,
-	"16G":  4,
+type cpuTier struct {
+	maxMemoryMiB float64
+	cpu          float64
+}
+
+// The CPUs allocated to the memory of a function, e.g. 128M or 128Mi => 0.083
+var availableCPUs = []cpuTier{
+	{maxMemoryMiB: 128, cpu: 0.083},
+	{maxMemoryMiB: 256, cpu: 0.167},
+	{maxMemoryMiB: 512, cpu: 0.333},
+	{maxMemoryMiB: 1024, cpu: 0.583},
+	{maxMemoryMiB: 2048, cpu: 1},
+	{maxMemoryMiB: 4096, cpu: 2},
+	{maxMemoryMiB: 8192, cpu: 2},
+	{maxMemoryMiB: 16384, cpu: 4},
+	{maxMemoryMiB: 32768, cpu: 8},
+}
+
+// parseAvailableMemory returns the memory MiB of the function.
+// Cloud Functions reads M and G as binary units, e.g. 256M => 256, unlike the Kubernetes-style quantities.
+func parseAvailableMemory(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasSuffix(value, "M") || strings.HasSuffix(value, "G") {
+		value += "i"
+	}
+
+	return utils.ParseMemoryMiB(value)
+}
+
+func getAvailableCPU(memoryMiB float64) (float64, bool) {
+	for _, tier := range availableCPUs {
+		if memoryMiB <= tier.maxMemoryMiB {
+			return tier.cpu, true
+		}
+	}
+
+	return 0, false
 }
 
 type CloudFunctions struct {
-	id     string
-	region string
-	cost   float64
+	id         string
+	region     string
+	cost       float64
+	references []string
 }
 
 type Function struct {
@@ -37,6 +67,7 @@ type Function struct {
 	availableMemory  string
 	maxInstanceCount int
 	minInstanceCount int
+	references       []string
 }
 
 type Revision struct {
@@ -47,9 +78,8 @@ type Container struct {
 	limits map[string]string
 }
 
-func getFunction(projectID string, hostName string) (Function, error) {
-	ctx := context.Background()
-	c, err := functions.NewFunctionClient(ctx)
+func getFunction(ctx context.Context, projectID string, hostName string) (Function, error) {
+	c, err := functions.NewFunctionClient(ctx, recorder.GRPCClientOptions()...)
 	if err != nil {
 		return Function{}, err
 	}
@@ -74,42 +104,110 @@ func getFunction(projectID string, hostName string) (Function, error) {
 		}
 
 		if u.Host == hostName {
-			return Function{
-				name:             resp.GetName(),
-				availableMemory:  resp.ServiceConfig.GetAvailableMemory(),
-				maxInstanceCount: int(resp.ServiceConfig.GetMaxInstanceCount()),
-				minInstanceCount: int(resp.ServiceConfig.GetMinInstanceCount()),
-			}, nil
+			return newFunction(resp), nil
 		}
 	}
 
 	return Function{}, fmt.Errorf("Cloud Functions Function was not found.")
 }
 
-func GetCloudFunctions(projectID string, hostName string) (CloudFunctions, bool) {
-	function, err := getFunction(projectID, hostName)
+func newFunction(resp *functionspb.Function) Function {
+	var references []string
+	for _, value := range resp.GetServiceConfig().GetEnvironmentVariables() {
+		if value != "" {
+			references = append(references, value)
+		}
+	}
+
+	return Function{
+		references:       references,
+		name:             resp.GetName(),
+		availableMemory:  resp.GetServiceConfig().GetAvailableMemory(),
+		maxInstanceCount: int(resp.GetServiceConfig().GetMaxInstanceCount()),
+		minInstanceCount: int(resp.GetServiceConfig().GetMinInstanceCount()),
+	}
+}
+
+func (f Function) toCloudFunctions() (CloudFunctions, error) {
+	mem, err := parseAvailableMemory(f.availableMemory)
 	if err != nil {
-		return CloudFunctions{}, false
+		return CloudFunctions{}, err
+	}
+
+	cpu, ok := getAvailableCPU(mem)
+	if !ok {
+		return CloudFunctions{}, fmt.Errorf("Unknown memory spec: %s", f.availableMemory)
 	}
 
-	mem, err := strconv.Atoi(function.availableMemory[:len(function.availableMemory)-1]) // Drop "M", "G"
+	avgInstanceCount := (f.maxInstanceCount + f.minInstanceCount) / 2
+
+	return CloudFunctions{
+		id:         path.Base(f.name),
+		region:     strings.Split(f.name, "/")[3],
+		cost:       (cpu*cost.SERVERLESS_COST_PER_CPU_CORE + mem*cost.SERVERLESS_COST_PER_MEM_MIB) * float64(avgInstanceCount),
+		references: f.references,
+	}, nil
+}
+
+func GetCloudFunctions(ctx context.Context, projectID string, hostName string) (CloudFunctions, bool) {
+	function, err := getFunction(ctx, projectID, hostName)
 	if err != nil {
 		return CloudFunctions{}, false
 	}
 
-	cpu, ok := availableCPUs[function.availableMemory]
-	if !ok {
-		log.Printf("Unknown memory spec")
+	x, err := function.toCloudFunctions()
+	if err != nil {
+		log.Println(err)
 		return CloudFunctions{}, false
 	}
 
-	avgInstanceCount := (function.maxInstanceCount + function.minInstanceCount) / 2
+	return x, true
+}
 
-	return CloudFunctions{
-		id:     path.Base(function.name),
-		region: strings.Split(function.name, "/")[3],
-		cost:   (cpu*cost.SERVERLESS_COST_PER_CPU_CORE + float64(mem)*cost.SERVERLESS_COST_PER_MEM_MIB) * float64(avgInstanceCount),
-	}, true
+func GetCloudFunction(ctx context.Context, projectID string, region string, name string) (CloudFunctions, error) {
+	c, err := functions.NewFunctionClient(ctx, recorder.GRPCClientOptions()...)
+	if err != nil {
+		return CloudFunctions{}, err
+	}
+	defer c.Close()
+
+	req := &functionspb.GetFunctionRequest{
+		Name: fmt.Sprintf("projects/%s/locations/%s/functions/%s", projectID, region, name),
+	}
+	resp, err := c.GetFunction(ctx, req)
+	if err != nil {
+		return CloudFunctions{}, err
+	}
+
+	return newFunction(resp).toCloudFunctions()
+}
+
+// ListFunctionIDs returns every function ID in the region
+func ListFunctionIDs(ctx context.Context, projectID string, region string) ([]string, error) {
+	c, err := functions.NewFunctionClient(ctx, recorder.GRPCClientOptions()...)
+	if err != nil {
+		return []string{}, err
+	}
+	defer c.Close()
+
+	req := &functionspb.ListFunctionsRequest{
+		Parent: fmt.Sprintf("projects/%s/locations/%s", projectID, region),
+	}
+	var ids []string
+	it := c.ListFunctions(ctx, req)
+	for {
+		resp, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return []string{}, err
+		}
+
+		ids = append(ids, path.Base(resp.GetName()))
+	}
+
+	return ids, nil
 }
 
 func (r CloudFunctions) GetID() string {
@@ -125,9 +223,13 @@ func (r CloudFunctions) GetCost() float64 {
 }
 
 func (r CloudFunctions) GetRegion() string {
-	return ""
+	return r.region
 }
 
 func (r CloudFunctions) GetZone() string {
 	return ""
 }
+
+func (r CloudFunctions) GetDatabaseReferences() []string {
+	return r.references
+}
@@ -0,0 +1,55 @@
+package cloudfunctions
+
+import (
+	"testing"
+)
+
+func TestAvailableMemory(t *testing.T) {
+	tests := []struct {
+		availableMemory string
+		wantMemoryMiB   float64
+		wantCPU         float64
+		wantErr         bool
+	}{
+		{availableMemory: "128M", wantMemoryMiB: 128, wantCPU: 0.083},
+		{availableMemory: "256M", wantMemoryMiB: 256, wantCPU: 0.167},
+		{availableMemory: "512M", wantMemoryMiB: 512, wantCPU: 0.333},
+		{availableMemory: "1G", wantMemoryMiB: 1024, wantCPU: 0.583},
+		{availableMemory: "2G", wantMemoryMiB: 2048, wantCPU: 1},
+		{availableMemory: "4G", wantMemoryMiB: 4096, wantCPU: 2},
+		{availableMemory: "8G", wantMemoryMiB: 8192, wantCPU: 2},
+		{availableMemory: "16G", wantMemoryMiB: 16384, wantCPU: 4},
+		{availableMemory: "32G", wantMemoryMiB: 32768, wantCPU: 8},
+		{availableMemory: "256Mi", wantMemoryMiB: 256, wantCPU: 0.167},
+		{availableMemory: "1Gi", wantMemoryMiB: 1024, wantCPU: 0.583},
+		// Memory between the tiers gets the CPUs of the next tier
+		{availableMemory: "384Mi", wantMemoryMiB: 384, wantCPU: 0.333},
+		{availableMemory: "256MB", wantErr: true},
+		{availableMemory: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		mem, err := parseAvailableMemory(tt.availableMemory)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseAvailableMemory(%q) error = %v, wantErr %t", tt.availableMemory, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if mem != tt.wantMemoryMiB {
+			t.Errorf("parseAvailableMemory(%q) = %v, want %v", tt.availableMemory, mem, tt.wantMemoryMiB)
+		}
+
+		cpu, ok := getAvailableCPU(mem)
+		if !ok || cpu != tt.wantCPU {
+			t.Errorf("getAvailableCPU(%v) = %v, %t, want %v", mem, cpu, ok, tt.wantCPU)
+		}
+	}
+}
+
+func TestGetAvailableCPUOverLimit(t *testing.T) {
+	if cpu, ok := getAvailableCPU(65536); ok {
+		t.Errorf("getAvailableCPU(65536) = %v, want no tier", cpu)
+	}
+}
@@ -6,21 +6,33 @@ import (
 	"log"
 	"net/url"
 	"path"
-	"strconv"
 	"strings"
 
 	asset "cloud.google.com/go/asset/apiv1"
 	run "cloud.google.com/go/run/apiv2"
+	"github.com/mittz/roleplay-webapp-assess/architecture/inventory"
 	"github.com/mittz/roleplay-webapp-assess/cost"
+	"github.com/mittz/roleplay-webapp-assess/recorder"
+	"github.com/mittz/roleplay-webapp-assess/utils"
 	"google.golang.org/api/iterator"
 	assetpb "google.golang.org/genproto/googleapis/cloud/asset/v1"
 	runpb "google.golang.org/genproto/googleapis/cloud/run/v2"
+	iampb "google.golang.org/genproto/googleapis/iam/v1"
 )
 
 type CloudRun struct {
-	id     string
-	region string
-	cost   float64
+	id         string
+	region     string
+	cost       float64
+	references []string
+	access     Access
+}
+
+// Access is who can reach the service and which identity the service runs as
+type Access struct {
+	Ingress              string
+	ServiceAccount       string
+	AllowUnauthenticated bool
 }
 
 type Service struct {
@@ -29,19 +41,65 @@ type Service struct {
 }
 
 type Revision struct {
+	name             string
 	containers       []Container
-	avgInstanceCount int
+	minInstanceCount int
+	maxInstanceCount int
+	concurrency      int
 }
 
 type Container struct {
-	limits map[string]string
+	limits  map[string]string
+	cpuIdle bool
 }
 
-func getService(projectID string, hostName string) (Service, error) {
-	scope := fmt.Sprintf("projects/%s", projectID)
-	ctx := context.Background()
+// getResources returns the CPUs and the memory MiB of the limits of the container, e.g. cpu: 1000m, memory: 512Mi
+func (c Container) getResources() (float64, float64, error) {
+	cpu, err := utils.ParseCPU(c.limits["cpu"])
+	if err != nil {
+		return 0, 0, err
+	}
 
-	client, err := asset.NewClient(ctx)
+	mem, err := utils.ParseMemoryMiB(c.limits["memory"])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return cpu, mem, nil
+}
+
+// getBillingRate returns the rate of the CPU allocation of the container against the request-only allocation
+func (c Container) getBillingRate() float64 {
+	if c.cpuIdle {
+		return cost.SERVERLESS_REQUEST_ONLY_COST_RATE
+	}
+
+	return cost.SERVERLESS_ALWAYS_ON_COST_RATE
+}
+
+// getService returns the service which serves the host.
+// The resources are searched when the snapshot doesn't have it, e.g. just created.
+func getService(ctx context.Context, projectID string, hostName string) (Service, error) {
+	if inv, ok := inventory.Get(projectID); ok {
+		for _, service := range inv.ListCloudRunServices() {
+			u, err := url.Parse(service.URL)
+			if err != nil {
+				return Service{}, err
+			}
+
+			if u.Host == hostName && service.Labels["goog-managed-by"] != "cloudfunctions" {
+				return Service{name: service.Name, location: service.Location}, nil
+			}
+		}
+	}
+
+	return searchService(ctx, projectID, hostName)
+}
+
+// searchService is replaced in the tests to resolve without the API
+var searchService = func(ctx context.Context, projectID string, hostName string) (Service, error) {
+	scope := fmt.Sprintf("projects/%s", projectID)
+	client, err := asset.NewClient(ctx, recorder.GRPCClientOptions()...)
 	if err != nil {
 		return Service{}, err
 	}
@@ -85,9 +143,8 @@ func getService(projectID string, hostName string) (Service, error) {
 	return Service{}, fmt.Errorf("Cloud Run Service was not found.")
 }
 
-func (s Service) GetRevisions() ([]Revision, error) {
-	ctx := context.Background()
-	c, err := run.NewRevisionsClient(ctx)
+func (s Service) GetRevisions(ctx context.Context) ([]Revision, error) {
+	c, err := run.NewRevisionsClient(ctx, recorder.GRPCClientOptions()...)
 	if err != nil {
 		return []Revision{}, err
 	}
@@ -107,91 +164,194 @@ func (s Service) GetRevisions() ([]Revision, error) {
 			return []Revision{}, err
 		}
 
-		minInstanceCount := resp.GetScaling().GetMaxInstanceCount()
-		maxInstanceCount := resp.GetScaling().GetMinInstanceCount()
+		minInstanceCount := resp.GetScaling().GetMinInstanceCount()
+		maxInstanceCount := resp.GetScaling().GetMaxInstanceCount()
 
 		var containers []Container
 		for _, condition := range resp.GetConditions() {
 			if condition.GetType() == "ResourcesAvailable" && condition.GetState() == runpb.Condition_CONDITION_SUCCEEDED {
 				for _, container := range resp.GetContainers() {
 					containers = append(containers, Container{
-						limits: container.Resources.GetLimits(),
+						limits:  container.GetResources().GetLimits(),
+						cpuIdle: container.GetResources().GetCpuIdle(),
 					})
 				}
 			}
 		}
 
 		revisions = append(revisions, Revision{
+			name:             path.Base(resp.GetName()),
 			containers:       containers,
-			avgInstanceCount: (int(minInstanceCount) + int(maxInstanceCount)) / 2,
+			minInstanceCount: int(minInstanceCount),
+			maxInstanceCount: int(maxInstanceCount),
+			concurrency:      int(resp.GetMaxInstanceRequestConcurrency()),
 		})
 	}
 
 	return revisions, nil
 }
 
-func GetCloudRun(projectID string, hostName string) (CloudRun, bool) {
-	service, err := getService(projectID, hostName)
+// getTraffic returns the percentage of the traffic which each revision of the service receives
+func getTraffic(service *runpb.Service) map[string]int {
+	traffic := make(map[string]int)
+	for _, status := range service.GetTrafficStatuses() {
+		revision := status.GetRevision()
+		if status.GetType() == runpb.TrafficTargetAllocationType_TRAFFIC_TARGET_ALLOCATION_TYPE_LATEST {
+			revision = service.GetLatestReadyRevision()
+		}
+		traffic[path.Base(revision)] += int(status.GetPercent())
+	}
+
+	return traffic
+}
+
+// GetExpectedInstanceCount estimates the instances of the revision receiving the percentage of the traffic.
+// The min instances are kept regardless of the traffic and the rest scales with the share of the load,
+// which the revision serves with fewer instances when an instance handles more concurrent requests.
+func (r Revision) GetExpectedInstanceCount(percent int) float64 {
+	expected := cost.ExpectedReplicas(cost.GetReplicaModel(), r.minInstanceCount, r.maxInstanceCount, 0)
+
+	concurrency := r.concurrency
+	if concurrency <= 0 {
+		concurrency = cost.SERVERLESS_DEFAULT_CONCURRENCY
+	}
+
+	instances := float64(r.minInstanceCount) + (expected-float64(r.minInstanceCount))*float64(percent)/100*cost.SERVERLESS_DEFAULT_CONCURRENCY/float64(concurrency)
+	if r.maxInstanceCount > 0 && instances > float64(r.maxInstanceCount) {
+		return float64(r.maxInstanceCount)
+	}
+
+	return instances
+}
+
+// getDatabaseReferences returns the Cloud SQL connections and the environment variables of the current template
+func getDatabaseReferences(service *runpb.Service) []string {
+	template := service.GetTemplate()
+	var references []string
+	// e.g. run.googleapis.com/cloudsql-instances: <projectID>:<region>:<instance>,...
+	for _, x := range strings.Split(template.GetAnnotations()["run.googleapis.com/cloudsql-instances"], ",") {
+		if x = strings.TrimSpace(x); x != "" {
+			references = append(references, x)
+		}
+	}
+
+	for _, volume := range template.GetVolumes() {
+		references = append(references, volume.GetCloudSqlInstance().GetInstances()...)
+	}
+
+	for _, container := range template.GetContainers() {
+		for _, env := range container.GetEnv() {
+			if env.GetValue() != "" {
+				references = append(references, env.GetValue())
+			}
+		}
+	}
+
+	return references
+}
+
+// getAccess returns the ingress settings, the service account and whether allUsers can invoke the service
+func getAccess(ctx context.Context, c *run.ServicesClient, service *runpb.Service) (Access, error) {
+	policy, err := c.GetIamPolicy(ctx, &iampb.GetIamPolicyRequest{Resource: service.GetName()})
+	if err != nil {
+		return Access{}, err
+	}
+
+	allowUnauthenticated := false
+	for _, binding := range policy.GetBindings() {
+		if binding.GetRole() != "roles/run.invoker" {
+			continue
+		}
+		for _, member := range binding.GetMembers() {
+			if member == "allUsers" {
+				allowUnauthenticated = true
+			}
+		}
+	}
+
+	return Access{
+		Ingress:              service.GetIngress().String(),
+		ServiceAccount:       service.GetTemplate().GetServiceAccount(),
+		AllowUnauthenticated: allowUnauthenticated,
+	}, nil
+}
+
+func GetCloudRun(ctx context.Context, projectID string, hostName string) (CloudRun, bool) {
+	service, err := getService(ctx, projectID, hostName)
+	if err != nil {
+		return CloudRun{}, false
+	}
+
+	// The traffic, the configuration and the access settings are all in the service
+	c, err := run.NewServicesClient(ctx, recorder.GRPCClientOptions()...)
 	if err != nil {
+		log.Printf("Failed to get the service %s: %v", service.name, err)
 		return CloudRun{}, false
 	}
+	defer c.Close()
 
-	revisions, err := service.GetRevisions()
+	resp, err := c.GetService(ctx, &runpb.GetServiceRequest{Name: service.name})
 	if err != nil {
+		log.Printf("Failed to get the service %s: %v", service.name, err)
 		return CloudRun{}, false
 	}
 
+	x, err := newCloudRun(ctx, c, service, resp)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		return CloudRun{}, false
+	}
+
+	return x, true
+}
+
+// newCloudRun estimates the cost of the fetched service from its revisions
+func newCloudRun(ctx context.Context, c *run.ServicesClient, service Service, resp *runpb.Service) (CloudRun, error) {
+	revisions, err := service.GetRevisions(ctx)
+	if err != nil {
+		return CloudRun{}, err
+	}
+
+	traffic := getTraffic(resp)
+
 	var totalCost float64
 	for _, revision := range revisions {
-		for _, container := range revision.containers {
-			cpuLimit, memLimit := container.limits["cpu"], container.limits["memory"]
-
-			var cpuNum, memNum int
-			if strings.Contains(cpuLimit, "m") {
-				cpuNum, err = strconv.Atoi(strings.TrimRight(cpuLimit, "m"))
-				if err != nil {
-					log.Printf("Error: %v", err)
-					return CloudRun{}, false
-				}
-				cpuNum /= 1000 // 1000, 2000, 3000 -> 1, 2, 3
-			} else {
-				cpuNum, err = strconv.Atoi(cpuLimit)
-				if err != nil {
-					log.Printf("Error: %v", err)
-					return CloudRun{}, false
-				}
-			}
+		// Revisions serving no traffic scale to zero unless they keep min instances
+		percent := traffic[revision.name]
+		if percent == 0 && revision.minInstanceCount == 0 {
+			continue
+		}
+		instanceCount := revision.GetExpectedInstanceCount(percent)
 
-			if strings.Contains(memLimit, "Mi") {
-				memNum, err = strconv.Atoi(strings.TrimRight(memLimit, "Mi"))
-				if err != nil {
-					return CloudRun{}, false
-				}
-			} else if strings.Contains(memLimit, "Gi") {
-				memNum, err = strconv.Atoi(strings.TrimRight(memLimit, "Gi"))
-				if err != nil {
-					return CloudRun{}, false
-				}
-				memNum *= 1024 // Gi to Mi
-			} else {
-				log.Printf("Unexpected case was found in memory limit: %s", memLimit)
-				return CloudRun{}, false
+		for _, container := range revision.containers {
+			cpuNum, memNum, err := container.getResources()
+			if err != nil {
+				return CloudRun{}, err
 			}
 
-			totalCost += (float64(cpuNum)*cost.SERVERLESS_COST_PER_CPU_CORE + float64(memNum)*cost.SERVERLESS_COST_PER_MEM_MIB) * float64(revision.avgInstanceCount)
+			totalCost += (cpuNum*cost.SERVERLESS_COST_PER_CPU_CORE + memNum*cost.SERVERLESS_COST_PER_MEM_MIB) * container.getBillingRate() * instanceCount
 		}
 	}
 
+	references := getDatabaseReferences(resp)
+
+	access, err := getAccess(ctx, c, resp)
+	if err != nil {
+		log.Printf("Failed to get the access settings of %s: %v", service.name, err)
+	}
+
 	return CloudRun{
-		id:     path.Base(service.name),
-		region: service.location,
-		cost:   totalCost,
-	}, true
+		id:         path.Base(service.name),
+		region:     service.location,
+		cost:       totalCost,
+		references: references,
+		access:     access,
+	}, nil
 }
 
-func GetCloudRunService(projectID string, region string, name string) (CloudRun, error) {
-	ctx := context.Background()
-	c, err := run.NewServicesClient(ctx)
+// GetCloudRunService returns the service which a serverless NEG refers to by its name
+func GetCloudRunService(ctx context.Context, projectID string, region string, name string) (CloudRun, error) {
+	c, err := run.NewServicesClient(ctx, recorder.GRPCClientOptions()...)
 	if err != nil {
 		return CloudRun{}, err
 	}
@@ -202,20 +362,38 @@ func GetCloudRunService(projectID string, region string, name string) (CloudRun,
 	}
 	resp, err := c.GetService(ctx, req)
 	if err != nil {
-		return CloudRun{}, nil
+		return CloudRun{}, fmt.Errorf("Cloud Run Service: %s doesn't exist in %s - %s: %w", name, projectID, region, err)
 	}
 
-	u, err := url.Parse(resp.GetUri())
+	return newCloudRun(ctx, c, Service{name: resp.GetName(), location: region}, resp)
+}
+
+// ListServiceIDs returns every service ID in the region
+func ListServiceIDs(ctx context.Context, projectID string, region string) ([]string, error) {
+	c, err := run.NewServicesClient(ctx, recorder.GRPCClientOptions()...)
 	if err != nil {
-		return CloudRun{}, err
+		return []string{}, err
 	}
+	defer c.Close()
 
-	x, exist := GetCloudRun(projectID, u.Host)
-	if exist {
-		return x, nil
+	req := &runpb.ListServicesRequest{
+		Parent: fmt.Sprintf("projects/%s/locations/%s", projectID, region),
 	}
+	var ids []string
+	it := c.ListServices(ctx, req)
+	for {
+		resp, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return []string{}, err
+		}
 
-	return CloudRun{}, fmt.Errorf("Cloud Run Service: %s doesn't exist in %s - %s", name, projectID, region)
+		ids = append(ids, path.Base(resp.GetName()))
+	}
+
+	return ids, nil
 }
 
 func (r CloudRun) GetID() string {
@@ -237,3 +415,19 @@ func (r CloudRun) GetRegion() string {
 func (r CloudRun) GetZone() string {
 	return ""
 }
+
+func (r CloudRun) GetDatabaseReferences() []string {
+	return r.references
+}
+
+func (r CloudRun) GetAccess() Access {
+	return r.access
+}
+
+func (r CloudRun) GetServiceAccounts() []string {
+	if r.access.ServiceAccount == "" {
+		return []string{}
+	}
+
+	return []string{r.access.ServiceAccount}
+}
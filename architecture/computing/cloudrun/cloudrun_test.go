@@ -0,0 +1,73 @@
+package cloudrun
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/mittz/roleplay-webapp-assess/architecture/inventory"
+)
+
+func TestContainerGetResources(t *testing.T) {
+	tests := []struct {
+		limits  map[string]string
+		wantCPU float64
+		wantMem float64
+		wantErr bool
+	}{
+		{limits: map[string]string{"cpu": "1", "memory": "512Mi"}, wantCPU: 1, wantMem: 512},
+		{limits: map[string]string{"cpu": "1000m", "memory": "512Mi"}, wantCPU: 1, wantMem: 512},
+		{limits: map[string]string{"cpu": "2", "memory": "1Gi"}, wantCPU: 2, wantMem: 1024},
+		{limits: map[string]string{"cpu": "4", "memory": "16Gi"}, wantCPU: 4, wantMem: 16384},
+		{limits: map[string]string{"cpu": "8", "memory": "32Gi"}, wantCPU: 8, wantMem: 32768},
+		// Below 1 CPU
+		{limits: map[string]string{"cpu": "500m", "memory": "256Mi"}, wantCPU: 0.5, wantMem: 256},
+		// Cloud Run reads the SI units as Kubernetes does
+		{limits: map[string]string{"cpu": "1", "memory": "512M"}, wantCPU: 1, wantMem: 512 * 1000 * 1000 / 1048576.0},
+		{limits: map[string]string{"cpu": "1"}, wantErr: true},
+		{limits: map[string]string{"cpu": "one", "memory": "512Mi"}, wantErr: true},
+		{limits: map[string]string{"cpu": "1", "memory": "512 MiB"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		cpu, mem, err := Container{limits: tt.limits}.getResources()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("getResources(%v) error = %v, wantErr %t", tt.limits, err, tt.wantErr)
+			continue
+		}
+		if math.Abs(cpu-tt.wantCPU) > 1e-9 || math.Abs(mem-tt.wantMem) > 1e-9 {
+			t.Errorf("getResources(%v) = %v, %v, want %v, %v", tt.limits, cpu, mem, tt.wantCPU, tt.wantMem)
+		}
+	}
+}
+
+func TestContainerGetBillingRate(t *testing.T) {
+	requestOnly := Container{cpuIdle: true}.getBillingRate()
+	alwaysOn := Container{cpuIdle: false}.getBillingRate()
+
+	if requestOnly != 1 {
+		t.Errorf("getBillingRate() of request-only allocation = %v, want the baseline 1", requestOnly)
+	}
+	if alwaysOn <= requestOnly {
+		t.Errorf("getBillingRate() of always-on allocation = %v, want more than request-only allocation %v", alwaysOn, requestOnly)
+	}
+}
+
+func TestGetServiceFallsBackToSearch(t *testing.T) {
+	defer inventory.Reset()
+	inventory.Set("p", nil)
+
+	original := searchService
+	defer func() { searchService = original }()
+	searchService = func(ctx context.Context, projectID string, hostName string) (Service, error) {
+		return Service{name: "projects/p/locations/us-central1/services/just-created", location: "us-central1"}, nil
+	}
+
+	service, err := getService(context.Background(), "p", "just-created-abc-uc.a.run.app")
+	if err != nil {
+		t.Fatalf("getService() error = %v", err)
+	}
+	if service.location != "us-central1" {
+		t.Errorf("getService() = %v, want the service from the search", service)
+	}
+}
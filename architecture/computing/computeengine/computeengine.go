@@ -2,22 +2,26 @@ package computeengine
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"path"
-	"strings"
 
 	compute "cloud.google.com/go/compute/apiv1"
+	"github.com/mittz/roleplay-webapp-assess/architecture/inventory"
 	"github.com/mittz/roleplay-webapp-assess/cost"
+	"github.com/mittz/roleplay-webapp-assess/recorder"
 	"github.com/mittz/roleplay-webapp-assess/utils"
 	"google.golang.org/api/iterator"
 	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
 )
 
 type ComputeEngine struct {
-	id     string
-	region string
-	zone   string
-	cost   float64
+	id              string
+	region          string
+	zone            string
+	cost            float64
+	references      []string
+	serviceAccounts []string
 }
 
 type Resource struct {
@@ -35,11 +39,10 @@ func calcCost(resource Resource) float64 {
 	return (float64(resource.CPU)*cost.GCE_COST_PER_CPU_CORE + float64(resource.MemoryMib)*cost.GCE_COST_PER_MEM_MIB) * sharedRate
 }
 
-func getMachineType(projectID string, zone string, machineType string) (Resource, error) {
-	ctx := context.Background()
-	c, err := compute.NewMachineTypesRESTClient(ctx)
+func getMachineType(ctx context.Context, projectID string, zone string, machineType string) (Resource, error) {
+	c, err := compute.NewMachineTypesRESTClient(ctx, recorder.HTTPClientOptions()...)
 	if err != nil {
-		return Resource{}, nil
+		return Resource{}, err
 	}
 	defer c.Close()
 
@@ -50,7 +53,7 @@ func getMachineType(projectID string, zone string, machineType string) (Resource
 	}
 	resp, err := c.Get(ctx, req)
 	if err != nil {
-		return Resource{}, nil
+		return Resource{}, err
 	}
 
 	return Resource{
@@ -60,58 +63,117 @@ func getMachineType(projectID string, zone string, machineType string) (Resource
 	}, nil
 }
 
-func GetComputeEngine(projectID string, hostIP string) (ComputeEngine, bool) {
-	ctx := context.Background()
-	c, err := compute.NewInstancesRESTClient(ctx)
+// GetMachineTypeCost returns the cost of a single instance of the machine type, e.g. e2-medium
+func GetMachineTypeCost(ctx context.Context, projectID string, zone string, machineType string) (float64, error) {
+	resource, err := getMachineType(ctx, projectID, zone, machineType)
+	if err != nil {
+		return 0, err
+	}
+
+	return calcCost(resource), nil
+}
+
+// NewComputeEngine returns an instance whose cost is already known, e.g. a member of a managed instance group
+func NewComputeEngine(name string, zone string, cost float64, references []string, serviceAccounts []string) ComputeEngine {
+	return ComputeEngine{
+		id:              name,
+		region:          utils.GetRegionFromZone(zone),
+		zone:            zone,
+		cost:            cost,
+		references:      references,
+		serviceAccounts: serviceAccounts,
+	}
+}
+
+// GetComputeEngine returns the running instance with the external IP address.
+// The product API is called when the snapshot doesn't have it, e.g. just created.
+func GetComputeEngine(ctx context.Context, projectID string, hostIP string) (ComputeEngine, bool) {
+	if inv, ok := inventory.Get(projectID); ok {
+		for _, instance := range inv.ListInstances() {
+			if hasNATIP(instance, hostIP) {
+				return newComputeEngineFromInstance(ctx, projectID, instance)
+			}
+		}
+	}
+
+	instances, err := listInstancesFromAPI(ctx, projectID)
 	if err != nil {
 		log.Println(err)
 		return ComputeEngine{}, false
 	}
+
+	for _, instance := range instances {
+		if hasNATIP(instance, hostIP) {
+			return newComputeEngineFromInstance(ctx, projectID, instance)
+		}
+	}
+
+	return ComputeEngine{}, false
+}
+
+// listInstancesFromAPI is replaced in the tests to resolve without the product API
+var listInstancesFromAPI = func(ctx context.Context, projectID string) ([]*computepb.Instance, error) {
+	c, err := compute.NewInstancesRESTClient(ctx, recorder.HTTPClientOptions()...)
+	if err != nil {
+		return nil, err
+	}
 	defer c.Close()
 
 	req := &computepb.AggregatedListInstancesRequest{Project: projectID}
+	var instances []*computepb.Instance
 	it := c.AggregatedList(ctx, req)
-
 	for {
 		resp, err := it.Next()
 		if err == iterator.Done {
 			break
 		}
 		if err != nil {
-			log.Println(err)
-			return ComputeEngine{}, false
+			return nil, err
 		}
 
-		for _, instance := range resp.Value.Instances {
-			if instance.GetStatus() == "RUNNING" {
-				for _, network := range instance.GetNetworkInterfaces() {
-					for _, config := range network.GetAccessConfigs() {
-						if config.GetNatIP() == hostIP {
-							resource, err := getMachineType(projectID, strings.Split(instance.GetMachineType(), "/")[8], path.Base(instance.GetMachineType()))
-							if err != nil {
-								log.Println(err)
-								return ComputeEngine{}, false
-							}
-
-							return ComputeEngine{
-								id:     instance.GetName(),
-								zone:   path.Base(instance.GetZone()),
-								region: utils.GetRegionFromZone(path.Base(instance.GetZone())),
-								cost:   calcCost(resource),
-							}, true
-						}
-					}
-				}
+		instances = append(instances, resp.Value.GetInstances()...)
+	}
+
+	return instances, nil
+}
+
+// hasNATIP returns true when the running instance has the external IP address
+func hasNATIP(instance *computepb.Instance, hostIP string) bool {
+	if instance.GetStatus() != "RUNNING" {
+		return false
+	}
+
+	for _, network := range instance.GetNetworkInterfaces() {
+		for _, config := range network.GetAccessConfigs() {
+			if config.GetNatIP() == hostIP {
+				return true
 			}
 		}
 	}
 
-	return ComputeEngine{}, false
+	return false
+}
+
+func newComputeEngineFromInstance(ctx context.Context, projectID string, instance *computepb.Instance) (ComputeEngine, bool) {
+	// The specs of the machine type aren't a part of the instance
+	resource, err := getMachineType(ctx, projectID, path.Base(instance.GetZone()), path.Base(instance.GetMachineType()))
+	if err != nil {
+		log.Println(err)
+		return ComputeEngine{}, false
+	}
+
+	return ComputeEngine{
+		id:              instance.GetName(),
+		zone:            path.Base(instance.GetZone()),
+		region:          utils.GetRegionFromZone(path.Base(instance.GetZone())),
+		cost:            calcCost(resource),
+		references:      getNetworkReferences(instance),
+		serviceAccounts: getServiceAccounts(instance.GetServiceAccounts()),
+	}, true
 }
 
-func GetComputeInstance(projectID string, zone string, name string) (ComputeEngine, error) {
-	ctx := context.Background()
-	c, err := compute.NewInstancesRESTClient(ctx)
+func GetComputeInstance(ctx context.Context, projectID string, zone string, name string) (ComputeEngine, error) {
+	c, err := compute.NewInstancesRESTClient(ctx, recorder.HTTPClientOptions()...)
 	if err != nil {
 		return ComputeEngine{}, err
 	}
@@ -127,19 +189,41 @@ func GetComputeInstance(projectID string, zone string, name string) (ComputeEngi
 		return ComputeEngine{}, err
 	}
 
-	resource, err := getMachineType(projectID, zone, path.Base(resp.GetMachineType()))
+	resource, err := getMachineType(ctx, projectID, zone, path.Base(resp.GetMachineType()))
 	if err != nil {
 		return ComputeEngine{}, err
 	}
 
 	return ComputeEngine{
-		id:     resp.GetName(),
-		region: utils.GetRegionFromZone(path.Base(resp.GetZone())),
-		zone:   path.Base(resp.GetZone()),
-		cost:   calcCost(resource),
+		id:              resp.GetName(),
+		region:          utils.GetRegionFromZone(path.Base(resp.GetZone())),
+		zone:            path.Base(resp.GetZone()),
+		cost:            calcCost(resource),
+		references:      getNetworkReferences(resp),
+		serviceAccounts: getServiceAccounts(resp.GetServiceAccounts()),
 	}, nil
 }
 
+// getServiceAccounts returns the emails of the service accounts attached to the instances
+func getServiceAccounts(serviceAccounts []*computepb.ServiceAccount) []string {
+	var emails []string
+	for _, serviceAccount := range serviceAccounts {
+		emails = append(emails, serviceAccount.GetEmail())
+	}
+
+	return emails
+}
+
+// Instances reach databases with private IP through their VPC networks
+func getNetworkReferences(instance *computepb.Instance) []string {
+	var references []string
+	for _, network := range instance.GetNetworkInterfaces() {
+		references = append(references, fmt.Sprintf("network:%s", path.Base(network.GetNetwork())))
+	}
+
+	return references
+}
+
 func (r ComputeEngine) GetID() string {
 	return r.id
 }
@@ -159,3 +243,11 @@ func (r ComputeEngine) GetRegion() string {
 func (r ComputeEngine) GetZone() string {
 	return r.zone
 }
+
+func (r ComputeEngine) GetDatabaseReferences() []string {
+	return r.references
+}
+
+func (r ComputeEngine) GetServiceAccounts() []string {
+	return r.serviceAccounts
+}
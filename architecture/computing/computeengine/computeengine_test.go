@@ -0,0 +1,29 @@
+package computeengine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mittz/roleplay-webapp-assess/architecture/inventory"
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+)
+
+func TestGetComputeEngineFallsBackToAPI(t *testing.T) {
+	defer inventory.Reset()
+	inventory.Set("p", nil)
+
+	original := listInstancesFromAPI
+	defer func() { listInstancesFromAPI = original }()
+	called := false
+	listInstancesFromAPI = func(ctx context.Context, projectID string) ([]*computepb.Instance, error) {
+		called = true
+		return nil, nil
+	}
+
+	if _, ok := GetComputeEngine(context.Background(), "p", "203.0.113.10"); ok {
+		t.Errorf("GetComputeEngine() found an instance which doesn't exist")
+	}
+	if !called {
+		t.Errorf("GetComputeEngine() didn't call the API when the snapshot missed")
+	}
+}
@@ -7,3 +7,14 @@ type Computing interface {
 	GetRegion() string
 	GetZone() string
 }
+
+// DatabaseReferrer is implemented by the products whose configuration refers to databases,
+// e.g. Cloud SQL connections, environment variables and VPC networks
+type DatabaseReferrer interface {
+	GetDatabaseReferences() []string
+}
+
+// ServiceAccountUser is implemented by the products which run as service accounts
+type ServiceAccountUser interface {
+	GetServiceAccounts() []string
+}
@@ -2,23 +2,28 @@ package kubernetesengine
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"log"
-	"os/exec"
-	"path/filepath"
+	"net/http"
 
 	container "cloud.google.com/go/container/apiv1"
+	"github.com/mittz/roleplay-webapp-assess/cost"
+	"github.com/mittz/roleplay-webapp-assess/recorder"
 	"github.com/mittz/roleplay-webapp-assess/utils"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
 	containerpb "google.golang.org/genproto/googleapis/container/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clientset "k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
+	"k8s.io/client-go/rest"
 )
 
 type GKECluster struct {
 	Name      string
 	Region    string
+	Location  string // Zone of a zonal cluster or region of a regional cluster
 	ProjectID string
 }
 
@@ -49,9 +54,8 @@ func (p Pod) GetZone() string {
 	return p.zone
 }
 
-func GetGKEClusters(projectID string) ([]GKECluster, error) {
-	ctx := context.Background()
-	c, err := container.NewClusterManagerClient(ctx)
+func GetGKEClusters(ctx context.Context, projectID string) ([]GKECluster, error) {
+	c, err := container.NewClusterManagerClient(ctx, recorder.GRPCClientOptions()...)
 	if err != nil {
 		return []GKECluster{}, err
 	}
@@ -72,6 +76,7 @@ func GetGKEClusters(projectID string) ([]GKECluster, error) {
 		clusters = append(clusters, GKECluster{
 			Name:      c.GetName(),
 			Region:    utils.GetRegionFromZone(c.GetLocation()),
+			Location:  c.GetLocation(),
 			ProjectID: projectID,
 		})
 	}
@@ -79,24 +84,43 @@ func GetGKEClusters(projectID string) ([]GKECluster, error) {
 	return clusters, nil
 }
 
-func (c GKECluster) GetPods() ([]Pod, error) {
-	if err := exec.Command(
-		"gcloud",
-		"container",
-		"clusters",
-		"get-credentials",
-		c.Name,
-		"--region",
-		c.Region,
-		"--project",
-		c.ProjectID,
-	).Err; err != nil {
-		return []Pod{}, err
+// getConfig builds the client config of the cluster from its endpoint and CA certificate.
+// Requests are authenticated with the application default credentials, so neither gcloud nor a kubeconfig is needed.
+func (c GKECluster) getConfig(ctx context.Context) (*rest.Config, error) {
+	client, err := container.NewClusterManagerClient(ctx, recorder.GRPCClientOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	cluster, err := client.GetCluster(ctx, &containerpb.GetClusterRequest{
+		Name: fmt.Sprintf("projects/%s/locations/%s/clusters/%s", c.ProjectID, c.Location, c.Name),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ca, err := base64.StdEncoding.DecodeString(cluster.GetMasterAuth().GetClusterCaCertificate())
+	if err != nil {
+		return nil, fmt.Errorf("CA certificate of the cluster: %s is invalid: %v", c.Name, err)
+	}
+
+	tokenSource, err := google.DefaultTokenSource(ctx, "https://www.googleapis.com/auth/cloud-platform")
+	if err != nil {
+		return nil, err
 	}
 
-	kubeconfig := filepath.Join(homedir.HomeDir(), ".kube", "config")
+	return &rest.Config{
+		Host:            fmt.Sprintf("https://%s", cluster.GetEndpoint()),
+		TLSClientConfig: rest.TLSClientConfig{CAData: ca},
+		WrapTransport: func(rt http.RoundTripper) http.RoundTripper {
+			return &oauth2.Transport{Source: tokenSource, Base: rt}
+		},
+	}, nil
+}
 
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+func (c GKECluster) GetPods(ctx context.Context) ([]Pod, error) {
+	config, err := c.getConfig(ctx)
 	if err != nil {
 		return []Pod{}, err
 	}
@@ -107,29 +131,50 @@ func (c GKECluster) GetPods() ([]Pod, error) {
 	}
 
 	kubeclient := client.CoreV1().Pods("default")
-	pods, err := kubeclient.List(context.Background(), metav1.ListOptions{})
+	pods, err := kubeclient.List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return []Pod{}, err
 	}
 
+	var x []Pod
 	for _, pod := range pods.Items {
-		log.Println(pod.String())
+		cpu, mem := getRequests(pod)
+		log.Printf("GKE Pod: %s - CPU: %.3f, Memory: %.1fMiB", pod.Name, cpu, mem)
+		x = append(x, Pod{
+			id:     pod.Name,
+			region: c.Region,
+			cost:   cpu*cost.GCE_COST_PER_CPU_CORE + mem*cost.GCE_COST_PER_MEM_MIB,
+		})
 	}
 
-	log.Fatal("Finish")
+	return x, nil
+}
+
+// getRequests returns the CPUs and the memory MiB which the containers of the pod request,
+// since pods are charged for the resources they request
+func getRequests(pod corev1.Pod) (float64, float64) {
+	var cpu, mem float64
+	for _, container := range pod.Spec.Containers {
+		if q, ok := container.Resources.Requests[corev1.ResourceCPU]; ok {
+			cpu += utils.CPUFromQuantity(q)
+		}
+		if q, ok := container.Resources.Requests[corev1.ResourceMemory]; ok {
+			mem += utils.MemoryMiBFromQuantity(q)
+		}
+	}
 
-	return []Pod{}, nil
+	return cpu, mem
 }
 
-func GetPodAll(projectID string) ([]Pod, error) {
-	clusters, err := GetGKEClusters(projectID)
+func GetPodAll(ctx context.Context, projectID string) ([]Pod, error) {
+	clusters, err := GetGKEClusters(ctx, projectID)
 	if err != nil {
 		return []Pod{}, err
 	}
 
 	var pods []Pod
 	for _, cluster := range clusters {
-		p, err := cluster.GetPods()
+		p, err := cluster.GetPods(ctx)
 		if err != nil {
 			return []Pod{}, err
 		}
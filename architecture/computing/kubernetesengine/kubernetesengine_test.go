@@ -0,0 +1,44 @@
+package kubernetesengine
+
+import (
+	"math"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func newContainer(cpu string, memory string) corev1.Container {
+	requests := corev1.ResourceList{}
+	if cpu != "" {
+		requests[corev1.ResourceCPU] = resource.MustParse(cpu)
+	}
+	if memory != "" {
+		requests[corev1.ResourceMemory] = resource.MustParse(memory)
+	}
+
+	return corev1.Container{Resources: corev1.ResourceRequirements{Requests: requests}}
+}
+
+func TestGetRequests(t *testing.T) {
+	tests := []struct {
+		name       string
+		containers []corev1.Container
+		wantCPU    float64
+		wantMem    float64
+	}{
+		{name: "millicores", containers: []corev1.Container{newContainer("250m", "512Mi")}, wantCPU: 0.25, wantMem: 512},
+		{name: "cores", containers: []corev1.Container{newContainer("2", "2Gi")}, wantCPU: 2, wantMem: 2048},
+		{name: "SI memory", containers: []corev1.Container{newContainer("1", "1G")}, wantCPU: 1, wantMem: 1000 * 1000 * 1000 / 1048576.0},
+		{name: "sidecar", containers: []corev1.Container{newContainer("500m", "256Mi"), newContainer("100m", "64Mi")}, wantCPU: 0.6, wantMem: 320},
+		{name: "no requests", containers: []corev1.Container{newContainer("", "")}, wantCPU: 0, wantMem: 0},
+	}
+
+	for _, tt := range tests {
+		pod := corev1.Pod{Spec: corev1.PodSpec{Containers: tt.containers}}
+		cpu, mem := getRequests(pod)
+		if math.Abs(cpu-tt.wantCPU) > 1e-9 || math.Abs(mem-tt.wantMem) > 1e-9 {
+			t.Errorf("%s: getRequests() = %v, %v, want %v, %v", tt.name, cpu, mem, tt.wantCPU, tt.wantMem)
+		}
+	}
+}
@@ -1,13 +1,14 @@
 package alloydb
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"log"
-	"os/exec"
+	"path"
 	"strings"
 
 	"github.com/mittz/roleplay-webapp-assess/cost"
+	"github.com/mittz/roleplay-webapp-assess/sla"
 )
 
 // CPU count: Memory MiB
@@ -24,43 +25,43 @@ type AlloyDB struct {
 	id               string
 	cost             float64
 	availabilityRate int
+	sla              float64
+	references       []string
 }
 
 type Cluster struct {
-	UID  string `json:"uid"`
-	Name string `json:"name"`
-}
-
-func getClusters(projectID string) ([]Cluster, error) {
-	out, err := exec.Command(
-		"gcloud",
-		"beta",
-		"alloydb",
-		"clusters",
-		"list",
-		fmt.Sprintf("--project=%s", projectID),
-		"--format=json",
-	).Output()
-	if err != nil {
-		return []Cluster{}, err
-	}
+	UID             string          `json:"uid"`
+	Name            string          `json:"name"`
+	Network         string          `json:"network"`
+	ClusterType     string          `json:"clusterType"`
+	SecondaryConfig SecondaryConfig `json:"secondaryConfig"`
+}
 
-	var clusters []Cluster
-	if err := json.Unmarshal(out, &clusters); err != nil {
+type SecondaryConfig struct {
+	PrimaryClusterName string `json:"primaryClusterName"`
+}
+
+func getClusters(ctx context.Context, client Client, projectID string) ([]Cluster, error) {
+	clusters, err := client.ListClusters(ctx, projectID)
+	if err != nil {
 		return []Cluster{}, err
 	}
 
 	if len(clusters) == 0 {
-		return []Cluster{}, fmt.Errorf("AlloyDB Cluster was not found.")
+		return []Cluster{}, ErrClusterNotFound
 	}
 
 	return clusters, nil
 }
 
 type Instance struct {
-	InstanceType   string         `json:"instanceType"`
-	MachineConfig  MachineConfig  `json:"machineConfig"`
-	ReadPoolConfig ReadPoolConfig `json:"readPoolConfig"`
+	Name             string         `json:"name"`
+	IPAddress        string         `json:"ipAddress"`
+	InstanceType     string         `json:"instanceType"`
+	AvailabilityType string         `json:"availabilityType"`
+	GCEZone          string         `json:"gceZone"`
+	MachineConfig    MachineConfig  `json:"machineConfig"`
+	ReadPoolConfig   ReadPoolConfig `json:"readPoolConfig"`
 }
 
 type MachineConfig struct {
@@ -71,60 +72,150 @@ type ReadPoolConfig struct {
 	NodeCount int `json:"nodeCount"`
 }
 
-func (c Cluster) GetInstances() ([]Instance, error) {
+func (c Cluster) GetInstances(ctx context.Context, client Client) ([]Instance, error) {
+	// "projects/<projectID>/locations/<region>/clusters/<clusterName>"
+	return client.ListInstances(ctx, c.Name)
+}
+
+func (c Cluster) GetRegion() string {
 	// "projects/<projectID>/locations/<region>/clusters/<clusterName>"
 	names := strings.Split(c.Name, "/")
-	projectID, region, clusterName := names[1], names[3], names[5]
-	out, err := exec.Command(
-		"gcloud",
-		"beta",
-		"alloydb",
-		"instances",
-		"list",
-		fmt.Sprintf("--cluster=%s", clusterName),
-		fmt.Sprintf("--region=%s", region),
-		fmt.Sprintf("--project=%s", projectID),
-		"--format=json",
-	).Output()
-	if err != nil {
-		return []Instance{}, err
+	if len(names) < 4 {
+		return ""
 	}
 
-	var instances []Instance
-	if err := json.Unmarshal(out, &instances); err != nil {
-		return []Instance{}, err
+	return names[3]
+}
+
+// GetNodeCount returns the number of nodes the instance runs on.
+// A REGIONAL primary or secondary instance has a standby node in another zone.
+func (i Instance) GetNodeCount() int {
+	if i.InstanceType == "READ_POOL" {
+		return i.ReadPoolConfig.NodeCount
 	}
 
-	return instances, nil
+	if i.AvailabilityType == "ZONAL" {
+		return 1
+	}
+
+	return 2
+}
+
+func (i Instance) calcCost() float64 {
+	cpuCount := i.MachineConfig.CPUCount
+	return (float64(cpuCount)*cost.ALLOYDB_COST_PER_CPU_CORE + float64(instanceTypes[cpuCount])*cost.ALLOYDB_COST_PER_MEM_MIB) * float64(i.GetNodeCount())
 }
 
-func GetAlloyDB(projectID string) (AlloyDB, bool) {
-	clusters, err := getClusters(projectID)
+// ListAlloyDB returns every cluster as a candidate of the database
+func ListAlloyDB(ctx context.Context, projectID string) ([]AlloyDB, error) {
+	client, err := NewClient(ctx)
 	if err != nil {
-		log.Printf("Failed to get AlloyDB clusters: %v", err)
-		return AlloyDB{}, false
+		return []AlloyDB{}, err
 	}
 
-	cluster := clusters[0] // Pick up one cluster if there are multiple ones
+	return ListAlloyDBWithClient(ctx, client, projectID)
+}
 
-	instances, err := cluster.GetInstances()
+// ListAlloyDBWithClient is the same as ListAlloyDB with the given client, e.g. a fake one
+func ListAlloyDBWithClient(ctx context.Context, client Client, projectID string) ([]AlloyDB, error) {
+	clusters, err := getClusters(ctx, client, projectID)
 	if err != nil {
-		log.Printf("Failed to get instances of AlloyDB cluster: %v", err)
-		return AlloyDB{}, false
+		return []AlloyDB{}, fmt.Errorf("Failed to get AlloyDB clusters: %w", err)
+	}
+
+	// Secondary clusters replicate their primary cluster, so they are a part of it rather than a candidate
+	secondaries := make(map[string][]Cluster)
+	for _, cluster := range clusters {
+		if cluster.ClusterType == "SECONDARY" {
+			secondaries[cluster.SecondaryConfig.PrimaryClusterName] = append(secondaries[cluster.SecondaryConfig.PrimaryClusterName], cluster)
+		}
+	}
+
+	var x []AlloyDB
+	for _, cluster := range clusters {
+		if cluster.ClusterType == "SECONDARY" {
+			continue
+		}
+
+		instances, err := cluster.GetInstances(ctx, client)
+		if err != nil {
+			return []AlloyDB{}, fmt.Errorf("Failed to get instances of AlloyDB cluster: %w", err)
+		}
+
+		var secondaryInstances []Instance
+		secondaryRegions := make(map[string]interface{})
+		for _, secondary := range secondaries[cluster.Name] {
+			y, err := secondary.GetInstances(ctx, client)
+			if err != nil {
+				return []AlloyDB{}, fmt.Errorf("Failed to get instances of AlloyDB secondary cluster: %w", err)
+			}
+
+			secondaryInstances = append(secondaryInstances, y...)
+			secondaryRegions[secondary.GetRegion()] = struct{}{}
+		}
+
+		x = append(x, newAlloyDB(cluster, instances, secondaryInstances, secondaryRegions))
+	}
+
+	if len(x) == 0 {
+		return []AlloyDB{}, ErrClusterNotFound
 	}
 
+	return x, nil
+}
+
+func newAlloyDB(cluster Cluster, instances []Instance, secondaryInstances []Instance, secondaryRegions map[string]interface{}) AlloyDB {
 	var totalCost float64
+	references := []string{cluster.Name, path.Base(cluster.Name)}
+	if cluster.Network != "" {
+		references = append(references, fmt.Sprintf("network:%s", path.Base(cluster.Network)))
+	}
+
+	primaryAvailabilityType := ""
+	readPoolNodes := 0
 	for _, instance := range instances {
-		cpuCount := instance.MachineConfig.CPUCount
-		nodeCount := 2 // PRIMARY_INSTANCE with HA = 2 nodes
-		if instance.InstanceType == "READ_POOL" {
-			nodeCount = instance.ReadPoolConfig.NodeCount
+		totalCost += instance.calcCost()
+
+		switch instance.InstanceType {
+		case "PRIMARY":
+			primaryAvailabilityType = instance.AvailabilityType
+		case "READ_POOL":
+			readPoolNodes += instance.GetNodeCount()
 		}
 
-		totalCost += (float64(cpuCount)*cost.ALLOYDB_COST_PER_CPU_CORE + float64(instanceTypes[cpuCount])*cost.ALLOYDB_COST_PER_MEM_MIB) * float64(nodeCount)
+		if instance.IPAddress != "" {
+			references = append(references, instance.IPAddress)
+		}
 	}
 
-	return AlloyDB{id: clusters[0].UID, availabilityRate: 2, cost: totalCost}, true
+	for _, instance := range secondaryInstances {
+		totalCost += instance.calcCost()
+	}
+
+	// Read pool nodes are spread over the zones of the region. They keep serving reads in a zone outage
+	// but writes like checkouts still depend on the primary, so they don't raise the rate by themselves.
+	delete(secondaryRegions, cluster.GetRegion())
+	var availabilityRate int
+	if len(secondaryRegions) > 0 {
+		availabilityRate = 3
+	} else if primaryAvailabilityType == "REGIONAL" {
+		availabilityRate = 2
+	} else {
+		availabilityRate = 1
+	}
+
+	// Secondary clusters can be promoted when the region of the primary cluster is down
+	availabilities := []float64{sla.ZONAL_ASSUMED_SLA}
+	if primaryAvailabilityType == "REGIONAL" {
+		availabilities[0] = sla.ALLOYDB_SLA
+	}
+	for range secondaryRegions {
+		availabilities = append(availabilities, sla.ALLOYDB_SLA)
+	}
+
+	log.Printf("AlloyDB cluster: %s - Primary: %s, Read pool nodes: %d, Secondary regions: %d => Availability rate: %d", cluster.Name, primaryAvailabilityType, readPoolNodes, len(secondaryRegions), availabilityRate)
+
+	return AlloyDB{id: cluster.UID, availabilityRate: availabilityRate, sla: sla.Parallel(availabilities...), cost: totalCost, references: references}
 }
 
 func (r AlloyDB) GetID() string {
@@ -135,6 +226,10 @@ func (r AlloyDB) GetAvailabilityRate() int {
 	return r.availabilityRate
 }
 
+func (r AlloyDB) GetSLA() float64 {
+	return r.sla
+}
+
 func (r AlloyDB) GetCost() float64 {
 	return r.cost
 }
@@ -142,3 +237,7 @@ func (r AlloyDB) GetCost() float64 {
 func (r AlloyDB) SetCost(cost float64) {
 	r.cost = cost
 }
+
+func (r AlloyDB) GetReferences() []string {
+	return r.references
+}
@@ -0,0 +1,161 @@
+package alloydb
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+)
+
+type fakeClient struct {
+	clusters  []Cluster
+	instances map[string][]Instance
+	err       error
+}
+
+func (c fakeClient) ListClusters(ctx context.Context, projectID string) ([]Cluster, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return c.clusters, nil
+}
+
+func (c fakeClient) ListInstances(ctx context.Context, clusterName string) ([]Instance, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return c.instances[clusterName], nil
+}
+
+const (
+	primaryCluster   = "projects/p/locations/us-central1/clusters/primary"
+	secondaryCluster = "projects/p/locations/us-east1/clusters/secondary"
+)
+
+func newInstance(instanceType string, availabilityType string, cpuCount int, nodeCount int) Instance {
+	return Instance{
+		Name:             "instance",
+		IPAddress:        "10.0.0.2",
+		InstanceType:     instanceType,
+		AvailabilityType: availabilityType,
+		MachineConfig:    MachineConfig{CPUCount: cpuCount},
+		ReadPoolConfig:   ReadPoolConfig{NodeCount: nodeCount},
+	}
+}
+
+func TestListAlloyDBWithClient(t *testing.T) {
+	// 2 CPUs and 16 GiB of memory on a node
+	nodeCost := 2*1.0 + 16*1024*0.0025
+
+	tests := []struct {
+		name     string
+		client   fakeClient
+		wantIDs  []string
+		wantRate int
+		wantCost float64
+	}{
+		{
+			name: "zonal primary",
+			client: fakeClient{
+				clusters:  []Cluster{{UID: "primary", Name: primaryCluster, ClusterType: "PRIMARY"}},
+				instances: map[string][]Instance{primaryCluster: {newInstance("PRIMARY", "ZONAL", 2, 0)}},
+			},
+			wantIDs:  []string{"primary"},
+			wantRate: 1,
+			wantCost: nodeCost,
+		},
+		{
+			name: "regional primary with read pool",
+			client: fakeClient{
+				clusters: []Cluster{{UID: "primary", Name: primaryCluster, ClusterType: "PRIMARY"}},
+				instances: map[string][]Instance{primaryCluster: {
+					newInstance("PRIMARY", "REGIONAL", 2, 0),
+					newInstance("READ_POOL", "", 2, 3),
+				}},
+			},
+			wantIDs:  []string{"primary"},
+			wantRate: 2,
+			wantCost: nodeCost * 5,
+		},
+		{
+			name: "secondary cluster in another region",
+			client: fakeClient{
+				clusters: []Cluster{
+					{UID: "primary", Name: primaryCluster, ClusterType: "PRIMARY"},
+					{UID: "secondary", Name: secondaryCluster, ClusterType: "SECONDARY", SecondaryConfig: SecondaryConfig{PrimaryClusterName: primaryCluster}},
+				},
+				instances: map[string][]Instance{
+					primaryCluster:   {newInstance("PRIMARY", "REGIONAL", 2, 0)},
+					secondaryCluster: {newInstance("SECONDARY", "REGIONAL", 2, 0)},
+				},
+			},
+			wantIDs:  []string{"primary"},
+			wantRate: 3,
+			wantCost: nodeCost * 4,
+		},
+	}
+
+	for _, tt := range tests {
+		dbs, err := ListAlloyDBWithClient(context.Background(), tt.client, "p")
+		if err != nil {
+			t.Errorf("%s: ListAlloyDBWithClient() error = %v", tt.name, err)
+			continue
+		}
+
+		if len(dbs) != len(tt.wantIDs) {
+			t.Errorf("%s: ListAlloyDBWithClient() returned %d clusters, want %d", tt.name, len(dbs), len(tt.wantIDs))
+			continue
+		}
+		for i, db := range dbs {
+			if db.GetID() != tt.wantIDs[i] {
+				t.Errorf("%s: GetID() = %s, want %s", tt.name, db.GetID(), tt.wantIDs[i])
+			}
+		}
+
+		if dbs[0].GetAvailabilityRate() != tt.wantRate {
+			t.Errorf("%s: GetAvailabilityRate() = %d, want %d", tt.name, dbs[0].GetAvailabilityRate(), tt.wantRate)
+		}
+		if math.Abs(dbs[0].GetCost()-tt.wantCost) > 1e-9 {
+			t.Errorf("%s: GetCost() = %v, want %v", tt.name, dbs[0].GetCost(), tt.wantCost)
+		}
+	}
+}
+
+func TestListAlloyDBWithClientErrors(t *testing.T) {
+	_, err := ListAlloyDBWithClient(context.Background(), fakeClient{}, "p")
+	if !errors.Is(err, ErrClusterNotFound) {
+		t.Errorf("ListAlloyDBWithClient() without clusters error = %v, want %v", err, ErrClusterNotFound)
+	}
+
+	apiErr := &googleapi.Error{Code: http.StatusForbidden, Message: "Permission denied"}
+	_, err = ListAlloyDBWithClient(context.Background(), fakeClient{err: apiErr}, "p")
+	var x *googleapi.Error
+	if !errors.As(err, &x) || x.Code != http.StatusForbidden {
+		t.Errorf("ListAlloyDBWithClient() with an API error = %v, want %v", err, apiErr)
+	}
+}
+
+func TestRESTClientReturnsAPIErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/projects/p/locations/-/clusters" {
+			t.Errorf("Path = %s, want the clusters of the project", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error": {"code": 403, "message": "Permission denied"}}`))
+	}))
+	defer server.Close()
+
+	client := &restClient{hc: server.Client(), endpoint: server.URL}
+	_, err := client.ListClusters(context.Background(), "p")
+	var x *googleapi.Error
+	if !errors.As(err, &x) || x.Code != http.StatusForbidden || x.Message != "Permission denied" {
+		t.Errorf("ListClusters() error = %v, want the 403 of the API", err)
+	}
+}
@@ -0,0 +1,119 @@
+package alloydb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/mittz/roleplay-webapp-assess/recorder"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	ALLOYDB_API_ENDPOINT = "https://alloydb.googleapis.com/v1"
+	ALLOYDB_API_SCOPE    = "https://www.googleapis.com/auth/cloud-platform"
+)
+
+var ErrClusterNotFound = errors.New("AlloyDB Cluster was not found.")
+
+// Client reads clusters and instances from the AlloyDB Admin API.
+// It can be replaced with a fake implementation to discover AlloyDB offline.
+// The REST implementation calls the GA v1 API and returns *googleapi.Error like the other API clients,
+// until cloud.google.com/go/alloydb/apiv1 is a dependency of the module.
+type Client interface {
+	ListClusters(ctx context.Context, projectID string) ([]Cluster, error)
+	ListInstances(ctx context.Context, clusterName string) ([]Instance, error)
+}
+
+type restClient struct {
+	hc       *http.Client
+	endpoint string
+}
+
+func NewClient(ctx context.Context) (Client, error) {
+	// Create an http.Client that uses Application Default Credentials.
+	hc, err := recorder.HTTPClient(ctx, ALLOYDB_API_SCOPE)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restClient{hc: hc, endpoint: ALLOYDB_API_ENDPOINT}, nil
+}
+
+// ListClusters lists the clusters in every region of the project
+func (c *restClient) ListClusters(ctx context.Context, projectID string) ([]Cluster, error) {
+	var clusters []Cluster
+	pageToken := ""
+	for {
+		var resp struct {
+			Clusters      []Cluster `json:"clusters"`
+			NextPageToken string    `json:"nextPageToken"`
+		}
+		if err := c.get(ctx, fmt.Sprintf("projects/%s/locations/-/clusters", projectID), pageToken, &resp); err != nil {
+			return nil, err
+		}
+
+		clusters = append(clusters, resp.Clusters...)
+		if resp.NextPageToken == "" {
+			break
+		}
+		pageToken = resp.NextPageToken
+	}
+
+	return clusters, nil
+}
+
+// ListInstances lists the instances of the cluster
+// e.g. clusterName: projects/<projectID>/locations/<region>/clusters/<clusterName>
+func (c *restClient) ListInstances(ctx context.Context, clusterName string) ([]Instance, error) {
+	var instances []Instance
+	pageToken := ""
+	for {
+		var resp struct {
+			Instances     []Instance `json:"instances"`
+			NextPageToken string     `json:"nextPageToken"`
+		}
+		if err := c.get(ctx, fmt.Sprintf("%s/instances", clusterName), pageToken, &resp); err != nil {
+			return nil, err
+		}
+
+		instances = append(instances, resp.Instances...)
+		if resp.NextPageToken == "" {
+			break
+		}
+		pageToken = resp.NextPageToken
+	}
+
+	return instances, nil
+}
+
+func (c *restClient) get(ctx context.Context, resource string, pageToken string, v interface{}) error {
+	u := fmt.Sprintf("%s/%s", c.endpoint, resource)
+	if pageToken != "" {
+		u = fmt.Sprintf("%s?pageToken=%s", u, url.QueryEscape(pageToken))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := googleapi.CheckResponse(resp); err != nil {
+		return err
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("Response of %s is invalid: %v", u, err)
+	}
+
+	return nil
+}
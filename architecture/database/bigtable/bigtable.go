@@ -0,0 +1,141 @@
+package bigtable
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/mittz/roleplay-webapp-assess/cost"
+	"github.com/mittz/roleplay-webapp-assess/recorder"
+	"github.com/mittz/roleplay-webapp-assess/sla"
+	"github.com/mittz/roleplay-webapp-assess/utils"
+	admin "google.golang.org/api/bigtableadmin/v2"
+)
+
+type Bigtable struct {
+	id               string
+	cost             float64
+	availabilityRate int
+	sla              float64
+	references       []string
+}
+
+type Instance struct {
+	Name     string
+	Clusters []Cluster
+}
+
+type Cluster struct {
+	Name       string
+	Zone       string
+	ServeNodes int64
+}
+
+func getInstances(ctx context.Context, projectID string) ([]Instance, error) {
+	service, err := admin.NewService(ctx, recorder.HTTPClientOptions()...)
+	if err != nil {
+		return []Instance{}, err
+	}
+
+	var instances []Instance
+	if err := service.Projects.Instances.List(fmt.Sprintf("projects/%s", projectID)).Pages(ctx, func(resp *admin.ListInstancesResponse) error {
+		for _, instance := range resp.Instances {
+			instances = append(instances, Instance{Name: instance.Name})
+		}
+		return nil
+	}); err != nil {
+		return []Instance{}, err
+	}
+
+	for i, instance := range instances {
+		if err := service.Projects.Instances.Clusters.List(instance.Name).Pages(ctx, func(resp *admin.ListClustersResponse) error {
+			for _, cluster := range resp.Clusters {
+				instances[i].Clusters = append(instances[i].Clusters, Cluster{
+					Name:       cluster.Name,
+					Zone:       path.Base(cluster.Location), // projects/<projectID>/locations/<zone>
+					ServeNodes: cluster.ServeNodes,
+				})
+			}
+			return nil
+		}); err != nil {
+			return []Instance{}, err
+		}
+	}
+
+	if len(instances) == 0 {
+		return []Instance{}, fmt.Errorf("Bigtable instance was not found.")
+	}
+
+	return instances, nil
+}
+
+// ListBigtable returns every instance as a candidate of the database
+func ListBigtable(ctx context.Context, projectID string) ([]Bigtable, error) {
+	instances, err := getInstances(ctx, projectID)
+	if err != nil {
+		return []Bigtable{}, err
+	}
+
+	var x []Bigtable
+	for _, instance := range instances {
+		x = append(x, newBigtable(instance))
+	}
+
+	return x, nil
+}
+
+func newBigtable(instance Instance) Bigtable {
+	var totalNodes int64
+	regions, zones := make(map[string]interface{}), make(map[string]interface{})
+	for _, cluster := range instance.Clusters {
+		totalNodes += cluster.ServeNodes
+		regions[utils.GetRegionFromZone(cluster.Zone)] = struct{}{}
+		zones[cluster.Zone] = struct{}{}
+	}
+
+	// Every cluster holds a replica of the data, so the instance survives the outage of a cluster's location
+	var availabilityRate int
+	var availability float64
+	if len(regions) > 1 {
+		availabilityRate = 3
+		availability = sla.BIGTABLE_MULTI_REGION_SLA
+	} else if len(zones) > 1 {
+		availabilityRate = 2
+		availability = sla.BIGTABLE_MULTI_ZONE_SLA
+	} else {
+		availabilityRate = 1
+		availability = sla.BIGTABLE_SINGLE_CLUSTER_SLA
+	}
+
+	return Bigtable{
+		id:               instance.Name,
+		cost:             float64(totalNodes) * cost.BIGTABLE_COST_PER_NODE,
+		availabilityRate: availabilityRate,
+		sla:              availability,
+		references:       []string{instance.Name, path.Base(instance.Name)},
+	}
+}
+
+func (r Bigtable) GetID() string {
+	return r.id
+}
+
+func (r Bigtable) GetAvailabilityRate() int {
+	return r.availabilityRate
+}
+
+func (r Bigtable) GetSLA() float64 {
+	return r.sla
+}
+
+func (r Bigtable) GetCost() float64 {
+	return r.cost
+}
+
+func (r Bigtable) SetCost(cost float64) {
+	r.cost = cost
+}
+
+func (r Bigtable) GetReferences() []string {
+	return r.references
+}
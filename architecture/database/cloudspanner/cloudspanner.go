@@ -3,29 +3,53 @@ package cloudspanner
 import (
 	"context"
 	"fmt"
-	"strings"
+	"log"
+	"path"
 
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
 	instance "cloud.google.com/go/spanner/admin/instance/apiv1"
 	"github.com/mittz/roleplay-webapp-assess/cost"
+	"github.com/mittz/roleplay-webapp-assess/recorder"
+	"github.com/mittz/roleplay-webapp-assess/sla"
 	"google.golang.org/api/iterator"
+	databasepb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
 	instancepb "google.golang.org/genproto/googleapis/spanner/admin/instance/v1"
 )
 
+const (
+	CONFIG_ZONAL        = "zonal"
+	CONFIG_REGIONAL     = "regional"
+	CONFIG_DUAL_REGION  = "dual-region"
+	CONFIG_MULTI_REGION = "multi-region"
+)
+
 type CloudSpanner struct {
 	id               string
 	cost             float64
 	availabilityRate int
+	sla              float64
+	references       []string
 }
 
 type Instance struct {
 	Name            string
+	NodeCount       int32
 	ProcessingUnits int32
 	Config          string
 }
 
-func getInstances(projectID string) ([]Instance, error) {
-	ctx := context.Background()
-	c, err := instance.NewInstanceAdminClient(ctx)
+type Replica struct {
+	Location string
+	Type     string
+}
+
+type InstanceConfig struct {
+	Name     string
+	Replicas []Replica
+}
+
+func getInstances(ctx context.Context, projectID string) ([]Instance, error) {
+	c, err := instance.NewInstanceAdminClient(ctx, recorder.GRPCClientOptions()...)
 	if err != nil {
 		return []Instance{}, err
 	}
@@ -48,6 +72,7 @@ func getInstances(projectID string) ([]Instance, error) {
 
 		instances = append(instances, Instance{
 			Name:            resp.GetName(),
+			NodeCount:       resp.GetNodeCount(),
 			ProcessingUnits: resp.GetProcessingUnits(),
 			Config:          resp.GetConfig(),
 		})
@@ -60,33 +85,178 @@ func getInstances(projectID string) ([]Instance, error) {
 	return instances, nil
 }
 
-func GetCloudSpanner(projectID string) (CloudSpanner, bool) {
-	instances, err := getInstances(projectID)
+// GetProcessingUnits returns the compute capacity of the instance.
+// An instance sized by nodes may report 0 processing units.
+func (i Instance) GetProcessingUnits() int32 {
+	if i.ProcessingUnits > 0 {
+		return i.ProcessingUnits
+	}
+
+	return i.NodeCount * cost.SPANNER_PROCESSING_UNITS_PER_NODE
+}
+
+func (i Instance) GetInstanceConfig(ctx context.Context) (InstanceConfig, error) {
+	c, err := instance.NewInstanceAdminClient(ctx, recorder.GRPCClientOptions()...)
 	if err != nil {
-		return CloudSpanner{}, false
+		return InstanceConfig{}, err
 	}
+	defer c.Close()
 
-	instance := instances[0] // Pick up one if there are multiple instances
+	req := &instancepb.GetInstanceConfigRequest{
+		Name: i.Config,
+	}
 
-	totalCost := float64(instance.ProcessingUnits) * cost.SPANNER_COST_PER_PROCESSING_UNIT
-	availabilityRate := 1
-	if strings.Contains(instance.Config, "regional") {
-		availabilityRate = 2
-	} else {
+	resp, err := c.GetInstanceConfig(ctx, req)
+	if err != nil {
+		return InstanceConfig{}, err
+	}
+
+	config := InstanceConfig{Name: resp.GetName()}
+	for _, replica := range resp.GetReplicas() {
+		config.Replicas = append(config.Replicas, Replica{
+			Location: replica.GetLocation(),
+			Type:     replica.GetType().String(),
+		})
+	}
+
+	return config, nil
+}
+
+func (i Instance) GetDatabases(ctx context.Context) ([]string, error) {
+	c, err := database.NewDatabaseAdminClient(ctx, recorder.GRPCClientOptions()...)
+	if err != nil {
+		return []string{}, err
+	}
+	defer c.Close()
+
+	req := &databasepb.ListDatabasesRequest{
+		Parent: i.Name,
+	}
+
+	var databases []string
+	it := c.ListDatabases(ctx, req)
+	for {
+		resp, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return []string{}, err
+		}
+
+		databases = append(databases, resp.GetName())
+	}
+
+	return databases, nil
+}
+
+// GetType classifies the config by the locations of the replicas which serve writes.
+// Read-only replicas don't take part in the write quorum, so they are left out.
+func (c InstanceConfig) GetType() string {
+	replicas := 0
+	regions := make(map[string]interface{})
+	for _, replica := range c.Replicas {
+		if replica.Type == instancepb.ReplicaInfo_READ_ONLY.String() {
+			continue
+		}
+		replicas++
+		regions[replica.Location] = struct{}{}
+	}
+
+	if len(regions) > 2 {
+		return CONFIG_MULTI_REGION
+	} else if len(regions) == 2 {
+		return CONFIG_DUAL_REGION
+	} else if replicas > 1 {
+		return CONFIG_REGIONAL
+	}
+
+	return CONFIG_ZONAL
+}
+
+func (c InstanceConfig) GetReadOnlyReplicaCount() int {
+	count := 0
+	for _, replica := range c.Replicas {
+		if replica.Type == instancepb.ReplicaInfo_READ_ONLY.String() {
+			count++
+		}
+	}
+
+	return count
+}
+
+// ListCloudSpanner returns every instance as a candidate of the database
+func ListCloudSpanner(ctx context.Context, projectID string) ([]CloudSpanner, error) {
+	instances, err := getInstances(ctx, projectID)
+	if err != nil {
+		return []CloudSpanner{}, err
+	}
+
+	var x []CloudSpanner
+	for _, instance := range instances {
+		config, err := instance.GetInstanceConfig(ctx)
+		if err != nil {
+			return []CloudSpanner{}, fmt.Errorf("Failed to get the instance config of Cloud Spanner: %w", err)
+		}
+
+		// The databases are the references to match the configuration of the apps
+		databases, err := instance.GetDatabases(ctx)
+		if err != nil {
+			return []CloudSpanner{}, fmt.Errorf("Failed to get the databases of Cloud Spanner: %w", err)
+		}
+
+		x = append(x, newCloudSpanner(instance, config, databases))
+	}
+
+	return x, nil
+}
+
+func newCloudSpanner(instance Instance, config InstanceConfig, databases []string) CloudSpanner {
+	processingUnits := float64(instance.GetProcessingUnits())
+	readOnlyReplicas := config.GetReadOnlyReplicaCount()
+	totalCost := processingUnits*cost.SPANNER_COST_PER_PROCESSING_UNIT +
+		processingUnits*float64(readOnlyReplicas)*cost.SPANNER_READ_ONLY_REPLICA_COST_PER_PROCESSING_UNIT
+
+	var availabilityRate int
+	var availability float64
+	configType := config.GetType()
+	switch configType {
+	case CONFIG_MULTI_REGION, CONFIG_DUAL_REGION:
 		availabilityRate = 3
+		availability = sla.SPANNER_MULTI_REGION_SLA
+	case CONFIG_REGIONAL:
+		availabilityRate = 2
+		availability = sla.SPANNER_REGIONAL_SLA
+	default:
+		availabilityRate = 1
+		availability = sla.SPANNER_ZONAL_SLA
+	}
+
+	log.Printf("Cloud Spanner instance: %s - Config: %s (%s), Processing units: %d, Read-only replicas: %d => Availability rate: %d", instance.Name, path.Base(instance.Config), configType, int(processingUnits), readOnlyReplicas, availabilityRate)
+
+	// The apps usually refer to the database by its ID rather than the full name, e.g. SPANNER_DATABASE=shop
+	references := []string{instance.Name, path.Base(instance.Name)}
+	for _, database := range databases {
+		references = append(references, database, path.Base(database))
 	}
 
 	return CloudSpanner{
 		id:               instance.Name,
 		cost:             totalCost,
 		availabilityRate: availabilityRate,
-	}, true
+		sla:              availability,
+		references:       references,
+	}
 }
 
 func (r CloudSpanner) GetID() string {
 	return r.id
 }
 
+func (r CloudSpanner) GetSLA() float64 {
+	return r.sla
+}
+
 func (r CloudSpanner) GetAvailabilityRate() int {
 	return r.availabilityRate
 }
@@ -98,3 +268,7 @@ func (r CloudSpanner) GetCost() float64 {
 func (r CloudSpanner) SetCost(cost float64) {
 	r.cost = cost
 }
+
+func (r CloudSpanner) GetReferences() []string {
+	return r.references
+}
@@ -0,0 +1,22 @@
+package cloudspanner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewCloudSpannerReferences(t *testing.T) {
+	instance := Instance{Name: "projects/p/instances/shop-instance", NodeCount: 1}
+	databases := []string{"projects/p/instances/shop-instance/databases/shop"}
+
+	got := newCloudSpanner(instance, InstanceConfig{}, databases).GetReferences()
+	want := []string{
+		"projects/p/instances/shop-instance",
+		"shop-instance",
+		"projects/p/instances/shop-instance/databases/shop",
+		"shop",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetReferences() = %v, want %v", got, want)
+	}
+}
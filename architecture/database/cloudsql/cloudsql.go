@@ -4,18 +4,25 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"path"
 	"strconv"
 	"strings"
 
+	"github.com/mittz/roleplay-webapp-assess/architecture/inventory"
 	"github.com/mittz/roleplay-webapp-assess/cost"
-	"golang.org/x/oauth2/google"
+	"github.com/mittz/roleplay-webapp-assess/recorder"
+	"github.com/mittz/roleplay-webapp-assess/sla"
 	"google.golang.org/api/sqladmin/v1"
 )
 
 type CloudSQL struct {
-	id               string
-	cost             float64
-	availabilityRate int
+	id                 string
+	cost               float64
+	availabilityRate   int
+	sla                float64
+	references         []string
+	publicIP           bool
+	authorizedNetworks []string
 }
 
 var (
@@ -25,11 +32,22 @@ var (
 	}
 )
 
-func getPrimaryInstance(projectID string) (*sqladmin.DatabaseInstance, error) {
-	ctx := context.Background()
+// getInstances returns the instances in the snapshot of the project.
+// The product API is called when the snapshot doesn't have any, e.g. just created.
+func getInstances(ctx context.Context, projectID string) ([]*sqladmin.DatabaseInstance, error) {
+	if inv, ok := inventory.Get(projectID); ok {
+		if instances := inv.ListCloudSQLInstances(); len(instances) > 0 {
+			return instances, nil
+		}
+	}
 
+	return listInstancesFromAPI(ctx, projectID)
+}
+
+// listInstancesFromAPI is replaced in the tests to resolve without the product API
+var listInstancesFromAPI = func(ctx context.Context, projectID string) ([]*sqladmin.DatabaseInstance, error) {
 	// Create an http.Client that uses Application Default Credentials.
-	hc, err := google.DefaultClient(ctx, sqladmin.SqlserviceAdminScope)
+	hc, err := recorder.HTTPClient(ctx, sqladmin.SqlserviceAdminScope)
 	if err != nil {
 		return nil, err
 	}
@@ -50,81 +68,77 @@ func getPrimaryInstance(projectID string) (*sqladmin.DatabaseInstance, error) {
 		return nil, fmt.Errorf("Cloud SQL Instance was not found.")
 	}
 
-	for _, instance := range instances.Items {
+	return instances.Items, nil
+}
+
+func getPrimaryInstances(instances []*sqladmin.DatabaseInstance) []*sqladmin.DatabaseInstance {
+	var primaryInstances []*sqladmin.DatabaseInstance
+	for _, instance := range instances {
 		if instance.State == "RUNNABLE" && instance.InstanceType == "CLOUD_SQL_INSTANCE" {
-			return instance, nil
+			primaryInstances = append(primaryInstances, instance)
 		}
 	}
 
-	return nil, fmt.Errorf("Cloud SQL Instance was not found.")
+	return primaryInstances
 }
 
-func getReplicaInstances(projectID string, primaryInstanceName string) ([]*sqladmin.DatabaseInstance, error) {
-	ctx := context.Background()
-
-	// Create an http.Client that uses Application Default Credentials.
-	hc, err := google.DefaultClient(ctx, sqladmin.SqlserviceAdminScope)
-	if err != nil {
-		return nil, err
-	}
-
-	// Create the Google Cloud SQL service.
-	service, err := sqladmin.New(hc)
-	if err != nil {
-		return nil, err
-	}
-
-	// List instances for the project ID.
-	instances, err := service.Instances.List(projectID).Do()
-	if err != nil {
-		return nil, err
-	}
-
-	if instances == nil {
-		return nil, fmt.Errorf("Cloud SQL Instance was not found.")
-	}
-
+func getReplicaInstances(instances []*sqladmin.DatabaseInstance, projectID string, primaryInstanceName string) []*sqladmin.DatabaseInstance {
 	var replicaInstances []*sqladmin.DatabaseInstance
-	for _, instance := range instances.Items {
+	for _, instance := range instances {
 		if instance.State == "RUNNABLE" && instance.InstanceType == "READ_REPLICA_INSTANCE" && instance.MasterInstanceName == fmt.Sprintf("%s:%s", projectID, primaryInstanceName) {
 			replicaInstances = append(replicaInstances, instance)
 		}
 	}
 
-	return replicaInstances, nil
+	return replicaInstances
 }
 
-func GetCloudSQL(projectID string) (CloudSQL, bool) {
-	primaryInstance, err := getPrimaryInstance(projectID)
+// ListCloudSQL returns every primary instance with its replicas as a candidate of the database
+func ListCloudSQL(ctx context.Context, projectID string) ([]CloudSQL, error) {
+	instances, err := getInstances(ctx, projectID)
 	if err != nil {
-		return CloudSQL{}, false
+		return []CloudSQL{}, err
 	}
 
-	replicaInstances, err := getReplicaInstances(projectID, primaryInstance.Name)
-	if err != nil {
-		return CloudSQL{}, false
+	var x []CloudSQL
+	for _, primaryInstance := range getPrimaryInstances(instances) {
+		replicaInstances := getReplicaInstances(instances, projectID, primaryInstance.Name)
+		db, err := newCloudSQL(primaryInstance, replicaInstances)
+		if err != nil {
+			log.Printf("Cloud SQL: %s was skipped: %v", primaryInstance.Name, err)
+			continue
+		}
+
+		x = append(x, db)
+	}
+
+	if len(x) == 0 {
+		return []CloudSQL{}, fmt.Errorf("Cloud SQL Instance was not found.")
 	}
 
+	return x, nil
+}
+
+func newCloudSQL(primaryInstance *sqladmin.DatabaseInstance, replicaInstances []*sqladmin.DatabaseInstance) (CloudSQL, error) {
 	haRate := 1
 	if primaryInstance.FailoverReplica != nil && primaryInstance.FailoverReplica.Available {
 		haRate = 2
 	}
 
 	if invalidMachineTypes[primaryInstance.Settings.Tier] {
-		log.Printf("The machine type of Cloud SQL: %s is not supported in this competition.", primaryInstance.Settings.Tier)
-		return CloudSQL{}, false
+		return CloudSQL{}, fmt.Errorf("The machine type of Cloud SQL: %s is not supported in this competition.", primaryInstance.Settings.Tier)
 	}
 
 	primaryInstaceTier := strings.Split(primaryInstance.Settings.Tier, "-")
 
 	cpu, err := strconv.Atoi(primaryInstaceTier[2])
 	if err != nil {
-		return CloudSQL{}, false
+		return CloudSQL{}, err
 	}
 
 	mem, err := strconv.Atoi(primaryInstaceTier[3])
 	if err != nil {
-		return CloudSQL{}, false
+		return CloudSQL{}, err
 	}
 
 	regions := map[string]interface{}{
@@ -138,12 +152,12 @@ func GetCloudSQL(projectID string) (CloudSQL, bool) {
 		replicaInstanceTier := strings.Split(replicaInstance.Settings.Tier, "-")
 		c, err := strconv.Atoi(replicaInstanceTier[2])
 		if err != nil {
-			return CloudSQL{}, false
+			return CloudSQL{}, err
 		}
 
 		m, err := strconv.Atoi(replicaInstanceTier[3])
 		if err != nil {
-			return CloudSQL{}, false
+			return CloudSQL{}, err
 		}
 
 		totalCost += float64(c)*cost.CLOUDSQL_COST_PER_CPU_CORE + float64(m)*cost.CLOUDSQL_COST_PER_MEM_MIB
@@ -158,11 +172,68 @@ func GetCloudSQL(projectID string) (CloudSQL, bool) {
 		availabilityRate = 1
 	}
 
+	// Replicas in the other regions can be promoted when the region of the primary is down
+	availabilities := []float64{getSLA(primaryInstance)}
+	for _, replicaInstance := range replicaInstances {
+		if replicaInstance.Region != primaryInstance.Region {
+			availabilities = append(availabilities, getSLA(replicaInstance))
+		}
+	}
+
+	publicIP, authorizedNetworks := getPublicAccess(primaryInstance, replicaInstances)
+
 	return CloudSQL{
-		id:               primaryInstance.Name,
-		cost:             totalCost,
-		availabilityRate: availabilityRate,
-	}, true
+		id:                 primaryInstance.Name,
+		cost:               totalCost,
+		availabilityRate:   availabilityRate,
+		sla:                sla.Parallel(availabilities...),
+		references:         getReferences(primaryInstance, replicaInstances),
+		publicIP:           publicIP,
+		authorizedNetworks: authorizedNetworks,
+	}, nil
+}
+
+func getSLA(instance *sqladmin.DatabaseInstance) float64 {
+	if instance.Settings != nil && instance.Settings.AvailabilityType == "REGIONAL" {
+		return sla.CLOUDSQL_HA_SLA
+	}
+
+	return sla.ZONAL_ASSUMED_SLA
+}
+
+// getPublicAccess returns whether any of the instances has a public IP address and the CIDR ranges allowed to connect to it
+func getPublicAccess(primaryInstance *sqladmin.DatabaseInstance, replicaInstances []*sqladmin.DatabaseInstance) (bool, []string) {
+	publicIP := false
+	var authorizedNetworks []string
+	for _, instance := range append([]*sqladmin.DatabaseInstance{primaryInstance}, replicaInstances...) {
+		if instance.Settings == nil || instance.Settings.IpConfiguration == nil || !instance.Settings.IpConfiguration.Ipv4Enabled {
+			continue
+		}
+
+		publicIP = true
+		for _, network := range instance.Settings.IpConfiguration.AuthorizedNetworks {
+			authorizedNetworks = append(authorizedNetworks, network.Value)
+		}
+	}
+
+	return publicIP, authorizedNetworks
+}
+
+// getReferences returns the connection names, names, IP addresses and private network of the instances
+func getReferences(primaryInstance *sqladmin.DatabaseInstance, replicaInstances []*sqladmin.DatabaseInstance) []string {
+	var references []string
+	for _, instance := range append([]*sqladmin.DatabaseInstance{primaryInstance}, replicaInstances...) {
+		references = append(references, instance.ConnectionName, instance.Name)
+		for _, ipAddress := range instance.IpAddresses {
+			references = append(references, ipAddress.IpAddress)
+		}
+
+		if instance.Settings != nil && instance.Settings.IpConfiguration != nil && instance.Settings.IpConfiguration.PrivateNetwork != "" {
+			references = append(references, fmt.Sprintf("network:%s", path.Base(instance.Settings.IpConfiguration.PrivateNetwork)))
+		}
+	}
+
+	return references
 }
 
 func (r CloudSQL) GetID() string {
@@ -173,6 +244,10 @@ func (r CloudSQL) GetAvailabilityRate() int {
 	return r.availabilityRate
 }
 
+func (r CloudSQL) GetSLA() float64 {
+	return r.sla
+}
+
 func (r CloudSQL) GetCost() float64 {
 	return r.cost
 }
@@ -180,3 +255,15 @@ func (r CloudSQL) GetCost() float64 {
 func (r CloudSQL) SetCost(cost float64) {
 	r.cost = cost
 }
+
+func (r CloudSQL) GetReferences() []string {
+	return r.references
+}
+
+func (r CloudSQL) HasPublicIP() bool {
+	return r.publicIP
+}
+
+func (r CloudSQL) GetAuthorizedNetworks() []string {
+	return r.authorizedNetworks
+}
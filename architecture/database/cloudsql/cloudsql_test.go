@@ -0,0 +1,28 @@
+package cloudsql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mittz/roleplay-webapp-assess/architecture/inventory"
+	sqladmin "google.golang.org/api/sqladmin/v1"
+)
+
+func TestGetInstancesFallsBackToAPI(t *testing.T) {
+	defer inventory.Reset()
+	inventory.Set("p", nil)
+
+	original := listInstancesFromAPI
+	defer func() { listInstancesFromAPI = original }()
+	listInstancesFromAPI = func(ctx context.Context, projectID string) ([]*sqladmin.DatabaseInstance, error) {
+		return []*sqladmin.DatabaseInstance{{Name: "just-created"}}, nil
+	}
+
+	instances, err := getInstances(context.Background(), "p")
+	if err != nil {
+		t.Fatalf("getInstances() error = %v", err)
+	}
+	if len(instances) != 1 || instances[0].Name != "just-created" {
+		t.Errorf("getInstances() = %v, want the instance from the API", instances)
+	}
+}
@@ -3,6 +3,17 @@ package database
 type Database interface {
 	GetID() string
 	GetAvailabilityRate() int
+	// GetSLA returns the expected availability in percent, e.g. 99.95
+	GetSLA() float64
 	GetCost() float64
 	SetCost(float64)
+	// GetReferences returns the values an application can use to connect to the database,
+	// e.g. connection name, IP address, instance name and "network:<name>" for VPC networks
+	GetReferences() []string
+}
+
+// PublicAccessor is implemented by the products which can be reached with public IP addresses
+type PublicAccessor interface {
+	HasPublicIP() bool
+	GetAuthorizedNetworks() []string
 }
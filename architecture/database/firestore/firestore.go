@@ -0,0 +1,129 @@
+package firestore
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/mittz/roleplay-webapp-assess/cost"
+	"github.com/mittz/roleplay-webapp-assess/recorder"
+	"github.com/mittz/roleplay-webapp-assess/sla"
+	admin "google.golang.org/api/firestore/v1"
+)
+
+const (
+	TYPE_NATIVE    = "FIRESTORE_NATIVE"
+	TYPE_DATASTORE = "DATASTORE_MODE"
+)
+
+// Multi-region locations replicate the data over multiple regions
+var multiRegionLocations = map[string]interface{}{
+	"nam5": struct{}{},
+	"nam7": struct{}{},
+	"eur3": struct{}{},
+}
+
+type Firestore struct {
+	id               string
+	cost             float64
+	availabilityRate int
+	sla              float64
+	references       []string
+}
+
+type Database struct {
+	Name       string
+	LocationID string
+	Type       string
+}
+
+func getDatabases(ctx context.Context, projectID string) ([]Database, error) {
+	service, err := admin.NewService(ctx, recorder.HTTPClientOptions()...)
+	if err != nil {
+		return []Database{}, err
+	}
+
+	resp, err := service.Projects.Databases.List(fmt.Sprintf("projects/%s", projectID)).Context(ctx).Do()
+	if err != nil {
+		return []Database{}, err
+	}
+
+	var databases []Database
+	for _, database := range resp.Databases {
+		databases = append(databases, Database{
+			Name:       database.Name,
+			LocationID: database.LocationId,
+			Type:       database.Type,
+		})
+	}
+
+	if len(databases) == 0 {
+		return []Database{}, fmt.Errorf("Firestore database was not found.")
+	}
+
+	return databases, nil
+}
+
+func (d Database) IsMultiRegion() bool {
+	_, ok := multiRegionLocations[d.LocationID]
+	return ok
+}
+
+// ListFirestore returns every database in Native mode and Datastore mode as a candidate of the database
+func ListFirestore(ctx context.Context, projectID string) ([]Firestore, error) {
+	databases, err := getDatabases(ctx, projectID)
+	if err != nil {
+		return []Firestore{}, err
+	}
+
+	var x []Firestore
+	for _, database := range databases {
+		x = append(x, newFirestore(database))
+	}
+
+	return x, nil
+}
+
+func newFirestore(database Database) Firestore {
+	// A regional location replicates the data over the zones of the region
+	availabilityRate := 2
+	availability := sla.FIRESTORE_REGIONAL_SLA
+	totalCost := cost.FIRESTORE_REGIONAL_COST_PER_DATABASE
+	if database.IsMultiRegion() {
+		availabilityRate = 3
+		availability = sla.FIRESTORE_MULTI_REGION_SLA
+		totalCost = cost.FIRESTORE_MULTI_REGION_COST_PER_DATABASE
+	}
+
+	return Firestore{
+		id:               database.Name,
+		cost:             totalCost,
+		availabilityRate: availabilityRate,
+		sla:              availability,
+		references:       []string{database.Name, path.Base(database.Name)},
+	}
+}
+
+func (r Firestore) GetID() string {
+	return r.id
+}
+
+func (r Firestore) GetAvailabilityRate() int {
+	return r.availabilityRate
+}
+
+func (r Firestore) GetSLA() float64 {
+	return r.sla
+}
+
+func (r Firestore) GetCost() float64 {
+	return r.cost
+}
+
+func (r Firestore) SetCost(cost float64) {
+	r.cost = cost
+}
+
+func (r Firestore) GetReferences() []string {
+	return r.references
+}
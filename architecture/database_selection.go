@@ -0,0 +1,120 @@
+package architecture
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/mittz/roleplay-webapp-assess/architecture/computing"
+	"github.com/mittz/roleplay-webapp-assess/architecture/database"
+)
+
+const (
+	// A Cloud SQL connection, an IP address or an instance name in the app configuration
+	SCORE_DIRECT_REFERENCE = 2
+	// The same VPC network as the app, e.g. private IP connection over VPC peering
+	SCORE_NETWORK_REFERENCE = 1
+)
+
+type databaseCandidate struct {
+	db    database.Database
+	score int
+}
+
+// getAppReferences collects the database references from the configuration of every app
+func getAppReferences(apps []computing.Computing) []string {
+	var references []string
+	for _, app := range apps {
+		if x, ok := app.(computing.DatabaseReferrer); ok {
+			references = append(references, x.GetDatabaseReferences()...)
+		}
+	}
+
+	return references
+}
+
+// scoreDatabase counts how strongly the apps refer to the database
+func scoreDatabase(db database.Database, appReferences []string) int {
+	return scoreReferences(db.GetReferences(), appReferences)
+}
+
+// scoreReferences counts how strongly the apps refer to a resource by its references
+func scoreReferences(references []string, appReferences []string) int {
+	score := 0
+	for _, dbReference := range references {
+		if dbReference == "" {
+			continue
+		}
+
+		for _, appReference := range appReferences {
+			if strings.HasPrefix(dbReference, "network:") {
+				if appReference == dbReference {
+					score += SCORE_NETWORK_REFERENCE
+				}
+				continue
+			}
+
+			// Environment variables can embed the reference, e.g. host=10.0.0.3 port=5432
+			if containsReference(appReference, dbReference) {
+				score += SCORE_DIRECT_REFERENCE
+			}
+		}
+	}
+
+	return score
+}
+
+// hasDirectReference returns true when any app refers to the resource itself, not only to its network
+func hasDirectReference(references []string, appReferences []string) bool {
+	for _, reference := range references {
+		if reference == "" || strings.HasPrefix(reference, "network:") {
+			continue
+		}
+
+		if scoreReferences([]string{reference}, appReferences) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// containsReference returns true when the app reference is the reference or embeds it as a whole token,
+// so 10.0.0.3 doesn't match 10.0.0.30 and db doesn't match db-staging
+func containsReference(appReference string, reference string) bool {
+	for i := 0; i+len(reference) <= len(appReference); {
+		j := strings.Index(appReference[i:], reference)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(reference)
+
+		if (start == 0 || !isTokenChar(appReference[start-1])) && (end == len(appReference) || !isTokenChar(appReference[end])) {
+			return true
+		}
+		i = start + 1
+	}
+
+	return false
+}
+
+// isTokenChar returns true for the characters of host names, IP addresses and resource names
+func isTokenChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '-' || c == '_'
+}
+
+// rankDatabases sorts the candidates by how strongly the apps refer to them.
+// The order of the candidates is kept when the scores are the same.
+func rankDatabases(dbs []database.Database, apps []computing.Computing) []databaseCandidate {
+	appReferences := getAppReferences(apps)
+
+	var candidates []databaseCandidate
+	for _, db := range dbs {
+		candidates = append(candidates, databaseCandidate{db: db, score: scoreDatabase(db, appReferences)})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	return candidates
+}
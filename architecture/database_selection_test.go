@@ -0,0 +1,116 @@
+package architecture
+
+import (
+	"testing"
+
+	"github.com/mittz/roleplay-webapp-assess/architecture/computing"
+	"github.com/mittz/roleplay-webapp-assess/architecture/database"
+)
+
+type fakeDatabase struct {
+	id         string
+	references []string
+}
+
+func (d fakeDatabase) GetID() string            { return d.id }
+func (d fakeDatabase) GetAvailabilityRate() int { return 0 }
+func (d fakeDatabase) GetSLA() float64          { return 0 }
+func (d fakeDatabase) GetCost() float64         { return 0 }
+func (d fakeDatabase) SetCost(float64)          {}
+func (d fakeDatabase) GetReferences() []string  { return d.references }
+
+type fakeApp struct {
+	id         string
+	references []string
+}
+
+func (a fakeApp) GetID() string                   { return a.id }
+func (a fakeApp) GetCost() float64                { return 0 }
+func (a fakeApp) SetCost(float64)                 {}
+func (a fakeApp) GetRegion() string               { return "" }
+func (a fakeApp) GetZone() string                 { return "" }
+func (a fakeApp) GetDatabaseReferences() []string { return a.references }
+
+func TestContainsReference(t *testing.T) {
+	tests := []struct {
+		appReference string
+		reference    string
+		want         bool
+	}{
+		{"10.0.0.3", "10.0.0.3", true},
+		{"host=10.0.0.3 port=5432", "10.0.0.3", true},
+		{"postgres://user@10.0.0.3:5432/db", "10.0.0.3", true},
+		{"10.0.0.30", "10.0.0.3", false},
+		{"110.0.0.3", "10.0.0.3", false},
+		{"db-staging", "db", false},
+		{"db_staging", "db", false},
+		{"db-staging,db", "db", true},
+		{"project:region:db", "project:region:db", true},
+		{"db", "db-staging", false},
+		{"", "db", false},
+	}
+
+	for _, tt := range tests {
+		if got := containsReference(tt.appReference, tt.reference); got != tt.want {
+			t.Errorf("containsReference(%q, %q) = %v, want %v", tt.appReference, tt.reference, got, tt.want)
+		}
+	}
+}
+
+func TestRankDatabases(t *testing.T) {
+	prod := fakeDatabase{id: "prod", references: []string{"project:region:prod", "10.0.0.3", "network:default"}}
+	staging := fakeDatabase{id: "staging", references: []string{"project:region:staging", "10.0.0.30", "network:default"}}
+	other := fakeDatabase{id: "other", references: []string{"", "10.1.0.3", "network:other"}}
+
+	tests := []struct {
+		name       string
+		dbs        []database.Database
+		apps       []computing.Computing
+		wantIDs    []string
+		wantScores []int
+	}{
+		{
+			name:       "no apps keeps the order",
+			dbs:        []database.Database{other, prod, staging},
+			wantIDs:    []string{"other", "prod", "staging"},
+			wantScores: []int{0, 0, 0},
+		},
+		{
+			name:       "direct reference ranks first",
+			dbs:        []database.Database{staging, prod},
+			apps:       []computing.Computing{fakeApp{id: "app", references: []string{"DB_HOST=10.0.0.3", "network:default"}}},
+			wantIDs:    []string{"prod", "staging"},
+			wantScores: []int{SCORE_DIRECT_REFERENCE + SCORE_NETWORK_REFERENCE, SCORE_NETWORK_REFERENCE},
+		},
+		{
+			name:       "network reference ranks before no reference",
+			dbs:        []database.Database{other, prod},
+			apps:       []computing.Computing{fakeApp{id: "app", references: []string{"network:default"}}},
+			wantIDs:    []string{"prod", "other"},
+			wantScores: []int{SCORE_NETWORK_REFERENCE, 0},
+		},
+		{
+			name: "references of every app are counted",
+			dbs:  []database.Database{prod, staging},
+			apps: []computing.Computing{
+				fakeApp{id: "app", references: []string{"project:region:staging"}},
+				fakeApp{id: "worker", references: []string{"10.0.0.30"}},
+			},
+			wantIDs:    []string{"staging", "prod"},
+			wantScores: []int{2 * SCORE_DIRECT_REFERENCE, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		got := rankDatabases(tt.dbs, tt.apps)
+		if len(got) != len(tt.wantIDs) {
+			t.Errorf("%s: rankDatabases() returned %d candidates, want %d", tt.name, len(got), len(tt.wantIDs))
+			continue
+		}
+		for i := range got {
+			if got[i].db.GetID() != tt.wantIDs[i] || got[i].score != tt.wantScores[i] {
+				t.Errorf("%s: rankDatabases()[%d] = %s (%d), want %s (%d)", tt.name, i, got[i].db.GetID(), got[i].score, tt.wantIDs[i], tt.wantScores[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	appengine "cloud.google.com/go/appengine/apiv1"
+	asset "cloud.google.com/go/asset/apiv1"
+	"github.com/mittz/roleplay-webapp-assess/recorder"
+	"google.golang.org/api/dns/v1"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+	runv1 "google.golang.org/api/run/v1"
+	appenginepb "google.golang.org/genproto/googleapis/appengine/v1"
+	assetpb "google.golang.org/genproto/googleapis/cloud/asset/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	SOURCE_CLOUD_DNS                 = "Cloud DNS"
+	SOURCE_CLOUD_RUN_DOMAIN_MAPPING  = "Cloud Run domain mapping"
+	SOURCE_APP_ENGINE_DOMAIN_MAPPING = "App Engine domain mapping"
+
+	TARGET_ADDRESS    = "Address"
+	TARGET_CLOUD_RUN  = "Cloud Run"
+	TARGET_APP_ENGINE = "App Engine"
+)
+
+// Mapping connects a hostname with the resource serving it
+type Mapping struct {
+	ProjectID  string
+	Host       string
+	Source     string
+	TargetType string
+	Target     string
+	Region     string
+}
+
+func (m Mapping) String() string {
+	if m.Region != "" {
+		return fmt.Sprintf("Host: %s => %s: %s (%s, %s)", m.Host, m.TargetType, m.Target, m.Region, m.Source)
+	}
+
+	return fmt.Sprintf("Host: %s => %s: %s (%s)", m.Host, m.TargetType, m.Target, m.Source)
+}
+
+// GetMappings returns every mapping of the hostname found in the project.
+// A product whose API is not available in the project is skipped.
+func GetMappings(ctx context.Context, projectID string, host string) []Mapping {
+	var mappings []Mapping
+
+	x, err := getRecordSets(ctx, projectID, host)
+	if err != nil {
+		log.Printf("GetMappings - getRecordSets: %v", err)
+	}
+	mappings = append(mappings, x...)
+
+	y, err := getCloudRunDomainMappings(ctx, projectID, host)
+	if err != nil {
+		log.Printf("GetMappings - getCloudRunDomainMappings: %v", err)
+	}
+	mappings = append(mappings, y...)
+
+	z, err := getAppEngineDomainMappings(ctx, projectID, host)
+	if err != nil {
+		log.Printf("GetMappings - getAppEngineDomainMappings: %v", err)
+	}
+	mappings = append(mappings, z...)
+
+	for i := range mappings {
+		mappings[i].ProjectID = projectID
+	}
+
+	return mappings
+}
+
+// getRecordSets looks up A/AAAA records of the host in the public and private managed zones.
+// A CNAME within the managed zones is followed once.
+func getRecordSets(ctx context.Context, projectID string, host string) ([]Mapping, error) {
+	service, err := dns.NewService(ctx, recorder.HTTPClientOptions()...)
+	if err != nil {
+		return nil, err
+	}
+
+	var zones []*dns.ManagedZone
+	if err := service.ManagedZones.List(projectID).Pages(ctx, func(resp *dns.ManagedZonesListResponse) error {
+		zones = append(zones, resp.ManagedZones...)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	lookup := func(name string) ([]*dns.ResourceRecordSet, error) {
+		var recordSets []*dns.ResourceRecordSet
+		for _, zone := range zones {
+			if !strings.HasSuffix(name, zone.DnsName) {
+				continue
+			}
+
+			if err := service.ResourceRecordSets.List(projectID, zone.Name).Name(name).Pages(ctx, func(resp *dns.ResourceRecordSetsListResponse) error {
+				recordSets = append(recordSets, resp.Rrsets...)
+				return nil
+			}); err != nil {
+				return nil, err
+			}
+		}
+
+		return recordSets, nil
+	}
+
+	recordSets, err := lookup(fmt.Sprintf("%s.", host))
+	if err != nil {
+		return nil, err
+	}
+
+	var mappings []Mapping
+	for _, recordSet := range recordSets {
+		switch recordSet.Type {
+		case "A", "AAAA":
+			for _, address := range recordSet.Rrdatas {
+				mappings = append(mappings, Mapping{Host: host, Source: SOURCE_CLOUD_DNS, TargetType: TARGET_ADDRESS, Target: address})
+			}
+		case "CNAME":
+			for _, cname := range recordSet.Rrdatas {
+				log.Printf("CNAME record of %s was found: %s", host, cname)
+				x, err := lookup(cname)
+				if err != nil {
+					return nil, err
+				}
+
+				for _, y := range x {
+					if y.Type != "A" && y.Type != "AAAA" {
+						continue
+					}
+
+					for _, address := range y.Rrdatas {
+						mappings = append(mappings, Mapping{Host: host, Source: SOURCE_CLOUD_DNS, TargetType: TARGET_ADDRESS, Target: address})
+					}
+				}
+			}
+		}
+	}
+
+	return mappings, nil
+}
+
+// getCloudRunDomainMappings finds the domain mapping through Cloud Asset Inventory
+// since domain mappings are served by the regional endpoint of each region
+func getCloudRunDomainMappings(ctx context.Context, projectID string, host string) ([]Mapping, error) {
+	client, err := asset.NewClient(ctx, recorder.GRPCClientOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	req := &assetpb.SearchAllResourcesRequest{
+		Scope: fmt.Sprintf("projects/%s", projectID),
+		AssetTypes: []string{
+			"run.googleapis.com/DomainMapping",
+		},
+	}
+
+	var mappings []Mapping
+	it := client.SearchAllResources(ctx, req)
+	for {
+		resource, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if resource.GetDisplayName() != host {
+			continue
+		}
+
+		region := resource.GetLocation()
+		service, err := runv1.NewService(ctx, append(recorder.HTTPClientOptions(), option.WithEndpoint(fmt.Sprintf("https://%s-run.googleapis.com/", region)))...)
+		if err != nil {
+			return nil, err
+		}
+
+		domainMapping, err := service.Namespaces.Domainmappings.Get(fmt.Sprintf("namespaces/%s/domainmappings/%s", projectID, host)).Context(ctx).Do()
+		if err != nil {
+			return nil, err
+		}
+
+		mappings = append(mappings, Mapping{
+			Host:       host,
+			Source:     SOURCE_CLOUD_RUN_DOMAIN_MAPPING,
+			TargetType: TARGET_CLOUD_RUN,
+			Target:     domainMapping.Spec.RouteName,
+			Region:     region,
+		})
+	}
+
+	return mappings, nil
+}
+
+func getAppEngineDomainMappings(ctx context.Context, projectID string, host string) ([]Mapping, error) {
+	c, err := appengine.NewDomainMappingsClient(ctx, recorder.GRPCClientOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	req := &appenginepb.GetDomainMappingRequest{
+		Name: fmt.Sprintf("apps/%s/domainMappings/%s", projectID, host),
+	}
+	resp, err := c.GetDomainMapping(ctx, req)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return []Mapping{
+		{
+			Host:       resp.GetId(),
+			Source:     SOURCE_APP_ENGINE_DOMAIN_MAPPING,
+			TargetType: TARGET_APP_ENGINE,
+			Target:     fmt.Sprintf("apps/%s", projectID),
+		},
+	}, nil
+}
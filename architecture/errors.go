@@ -0,0 +1,43 @@
+package architecture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrDiscoveryTimeout is matched by errors.Is when the discovery exceeded its deadline
+var ErrDiscoveryTimeout = errors.New("Discovery timed out")
+
+// DiscoveryError is returned when a discovery step was interrupted by its context
+type DiscoveryError struct {
+	Step string
+	Err  error
+}
+
+func (e *DiscoveryError) Error() string {
+	return fmt.Sprintf("Discovery of %s was interrupted: %v", e.Step, e.Err)
+}
+
+func (e *DiscoveryError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout reports whether the step was interrupted by the deadline, not by the cancellation
+func (e *DiscoveryError) Timeout() bool {
+	return errors.Is(e.Err, context.DeadlineExceeded)
+}
+
+func (e *DiscoveryError) Is(target error) bool {
+	return target == ErrDiscoveryTimeout && e.Timeout()
+}
+
+// checkContext returns a DiscoveryError if the context is done, since the lookups
+// of the step return partial results instead of the error of the context
+func checkContext(ctx context.Context, step string) error {
+	if err := ctx.Err(); err != nil {
+		return &DiscoveryError{Step: step, Err: err}
+	}
+
+	return nil
+}
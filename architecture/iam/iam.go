@@ -0,0 +1,56 @@
+package iam
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mittz/roleplay-webapp-assess/recorder"
+	"google.golang.org/api/cloudresourcemanager/v1"
+)
+
+const (
+	ROLE_OWNER  = "roles/owner"
+	ROLE_EDITOR = "roles/editor"
+)
+
+// ProjectRoles maps the members of the IAM policy of the project to their roles,
+// e.g. serviceAccount:123-compute@developer.gserviceaccount.com => [roles/editor]
+type ProjectRoles map[string][]string
+
+func GetProjectRoles(ctx context.Context, projectID string) (ProjectRoles, error) {
+	service, err := cloudresourcemanager.NewService(ctx, recorder.HTTPClientOptions()...)
+	if err != nil {
+		return ProjectRoles{}, err
+	}
+
+	policy, err := service.Projects.GetIamPolicy(projectID, &cloudresourcemanager.GetIamPolicyRequest{}).Context(ctx).Do()
+	if err != nil {
+		return ProjectRoles{}, err
+	}
+
+	roles := make(ProjectRoles)
+	for _, binding := range policy.Bindings {
+		// Conditional bindings don't always apply, so only the unconditional ones are counted
+		if binding.Condition != nil {
+			continue
+		}
+
+		for _, member := range binding.Members {
+			roles[member] = append(roles[member], binding.Role)
+		}
+	}
+
+	return roles, nil
+}
+
+// GetServiceAccountRoles returns the roles granted to the service account on the project
+func (r ProjectRoles) GetServiceAccountRoles(email string) []string {
+	return r[fmt.Sprintf("serviceAccount:%s", email)]
+}
+
+// IsDefaultComputeServiceAccount returns true for the Compute Engine default service account,
+// e.g. 123456789012-compute@developer.gserviceaccount.com
+func IsDefaultComputeServiceAccount(email string) bool {
+	return strings.HasSuffix(email, "-compute@developer.gserviceaccount.com")
+}
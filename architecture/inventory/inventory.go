@@ -0,0 +1,246 @@
+// Package inventory takes a single Cloud Asset Inventory snapshot of the project.
+// The product resolvers query it in memory and call the product APIs only for the details
+// which the asset data doesn't have, e.g. machine type specs.
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	asset "cloud.google.com/go/asset/apiv1"
+	"github.com/mittz/roleplay-webapp-assess/recorder"
+	"google.golang.org/api/iterator"
+	sqladmin "google.golang.org/api/sqladmin/v1"
+	assetpb "google.golang.org/genproto/googleapis/cloud/asset/v1"
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	ASSET_FORWARDING_RULE        = "compute.googleapis.com/ForwardingRule"
+	ASSET_GLOBAL_FORWARDING_RULE = "compute.googleapis.com/GlobalForwardingRule"
+	ASSET_INSTANCE               = "compute.googleapis.com/Instance"
+	ASSET_CLOUD_RUN_SERVICE      = "run.googleapis.com/Service"
+	ASSET_CLOUD_SQL_INSTANCE     = "sqladmin.googleapis.com/Instance"
+	ASSET_TARGET_HTTP_PROXY      = "compute.googleapis.com/TargetHttpProxy"
+	ASSET_TARGET_HTTPS_PROXY     = "compute.googleapis.com/TargetHttpsProxy"
+	ASSET_URL_MAP                = "compute.googleapis.com/UrlMap"
+	ASSET_BACKEND_SERVICE        = "compute.googleapis.com/BackendService"
+	ASSET_NETWORK_ENDPOINT_GROUP = "compute.googleapis.com/NetworkEndpointGroup"
+	ASSET_INSTANCE_GROUP         = "compute.googleapis.com/InstanceGroup"
+)
+
+var assetTypes = []string{
+	ASSET_FORWARDING_RULE,
+	ASSET_GLOBAL_FORWARDING_RULE,
+	ASSET_INSTANCE,
+	ASSET_CLOUD_RUN_SERVICE,
+	ASSET_CLOUD_SQL_INSTANCE,
+	ASSET_TARGET_HTTP_PROXY,
+	ASSET_TARGET_HTTPS_PROXY,
+	ASSET_URL_MAP,
+	ASSET_BACKEND_SERVICE,
+	ASSET_NETWORK_ENDPOINT_GROUP,
+	ASSET_INSTANCE_GROUP,
+}
+
+type Inventory struct {
+	projectID string
+	assets    map[string][]*assetpb.Asset
+}
+
+// CloudRunService is the part of a Cloud Run service which the discovery uses
+type CloudRunService struct {
+	Name     string // projects/<project>/locations/<region>/services/<name>
+	Location string
+	URL      string
+	Labels   map[string]string
+}
+
+var (
+	inventories = make(map[string]*Inventory)
+	mu          sync.RWMutex
+)
+
+// Load takes the snapshot of the project and makes it available to the product resolvers.
+// The snapshots of the other projects are kept, so the resources of multiple projects can be resolved.
+func Load(ctx context.Context, projectID string) (*Inventory, error) {
+	client, err := asset.NewClient(ctx, recorder.GRPCClientOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	req := &assetpb.ListAssetsRequest{
+		Parent:      fmt.Sprintf("projects/%s", projectID),
+		AssetTypes:  assetTypes,
+		ContentType: assetpb.ContentType_RESOURCE,
+	}
+
+	var assets []*assetpb.Asset
+	it := client.ListAssets(ctx, req)
+	for {
+		resp, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		assets = append(assets, resp)
+	}
+
+	return Set(projectID, assets), nil
+}
+
+// Set makes the assets available to the product resolvers as the snapshot of the project, e.g. a stub in the tests.
+// The snapshot lags behind the resources just created, so the resolvers call the product APIs when it misses.
+func Set(projectID string, assets []*assetpb.Asset) *Inventory {
+	x := &Inventory{projectID: projectID, assets: make(map[string][]*assetpb.Asset)}
+	for _, a := range assets {
+		x.assets[a.GetAssetType()] = append(x.assets[a.GetAssetType()], a)
+	}
+
+	mu.Lock()
+	inventories[projectID] = x
+	mu.Unlock()
+
+	return x
+}
+
+// Get returns the snapshot of the project if it has been loaded
+func Get(projectID string) (*Inventory, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	x, ok := inventories[projectID]
+	return x, ok
+}
+
+// Reset drops the snapshots, so the resolvers call the product APIs again
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	inventories = make(map[string]*Inventory)
+}
+
+func (i *Inventory) GetAssets(assetType string) []*assetpb.Asset {
+	return i.assets[assetType]
+}
+
+// unmarshalProto converts the asset data, which is the REST representation of the resource, into the message
+func unmarshalProto(a *assetpb.Asset, m proto.Message) error {
+	data, err := protojson.Marshal(a.GetResource().GetData())
+	if err != nil {
+		return err
+	}
+
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, m)
+}
+
+func unmarshalJSON(a *assetpb.Asset, v interface{}) error {
+	data, err := protojson.Marshal(a.GetResource().GetData())
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// GetComputeResource finds the Compute Engine resource by its path and unmarshals it into the message,
+// e.g. projects/<projectID>/global/urlMaps/<name>
+func (i *Inventory) GetComputeResource(assetType string, resourcePath string, m proto.Message) bool {
+	name := fmt.Sprintf("//compute.googleapis.com/%s", resourcePath)
+	for _, a := range i.assets[assetType] {
+		if a.GetName() != name {
+			continue
+		}
+
+		if err := unmarshalProto(a, m); err != nil {
+			log.Printf("inventory - GetComputeResource: %s: %v", a.GetName(), err)
+			return false
+		}
+		return true
+	}
+
+	return false
+}
+
+// ListForwardingRules returns the regional and global forwarding rules
+func (i *Inventory) ListForwardingRules() []*computepb.ForwardingRule {
+	var rules []*computepb.ForwardingRule
+	for _, assetType := range []string{ASSET_FORWARDING_RULE, ASSET_GLOBAL_FORWARDING_RULE} {
+		for _, a := range i.assets[assetType] {
+			rule := &computepb.ForwardingRule{}
+			if err := unmarshalProto(a, rule); err != nil {
+				log.Printf("inventory - ListForwardingRules: %s: %v", a.GetName(), err)
+				continue
+			}
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules
+}
+
+func (i *Inventory) ListInstances() []*computepb.Instance {
+	var instances []*computepb.Instance
+	for _, a := range i.assets[ASSET_INSTANCE] {
+		instance := &computepb.Instance{}
+		if err := unmarshalProto(a, instance); err != nil {
+			log.Printf("inventory - ListInstances: %s: %v", a.GetName(), err)
+			continue
+		}
+		instances = append(instances, instance)
+	}
+
+	return instances
+}
+
+func (i *Inventory) ListCloudSQLInstances() []*sqladmin.DatabaseInstance {
+	var instances []*sqladmin.DatabaseInstance
+	for _, a := range i.assets[ASSET_CLOUD_SQL_INSTANCE] {
+		instance := &sqladmin.DatabaseInstance{}
+		if err := unmarshalJSON(a, instance); err != nil {
+			log.Printf("inventory - ListCloudSQLInstances: %s: %v", a.GetName(), err)
+			continue
+		}
+		instances = append(instances, instance)
+	}
+
+	return instances
+}
+
+func (i *Inventory) ListCloudRunServices() []CloudRunService {
+	var services []CloudRunService
+	for _, a := range i.assets[ASSET_CLOUD_RUN_SERVICE] {
+		// The asset data is the Knative representation of the service
+		var service struct {
+			Metadata struct {
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+			Status struct {
+				URL string `json:"url"`
+			} `json:"status"`
+		}
+		if err := unmarshalJSON(a, &service); err != nil {
+			log.Printf("inventory - ListCloudRunServices: %s: %v", a.GetName(), err)
+			continue
+		}
+
+		services = append(services, CloudRunService{
+			Name:     strings.Join(strings.Split(a.GetName(), "/")[3:], "/"), // Drop "//run.googleapis.com/"
+			Location: a.GetResource().GetLocation(),
+			URL:      service.Status.URL,
+			Labels:   service.Metadata.Labels,
+		})
+	}
+
+	return services
+}
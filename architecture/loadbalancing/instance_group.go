@@ -0,0 +1,358 @@
+package loadbalancing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+
+	compute "cloud.google.com/go/compute/apiv1"
+	"github.com/mittz/roleplay-webapp-assess/architecture/computing/computeengine"
+	"github.com/mittz/roleplay-webapp-assess/cost"
+	"github.com/mittz/roleplay-webapp-assess/recorder"
+	"github.com/mittz/roleplay-webapp-assess/utils"
+	"google.golang.org/api/googleapi"
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	COMPUTE_API_ENDPOINT = "https://compute.googleapis.com/compute/v1"
+)
+
+type InstanceGroupManager struct {
+	Name             string
+	Zone             string
+	Region           string
+	Regional         bool
+	InstanceTemplate string // URL of a global or regional instance template
+	TargetSize       int
+	Autoscaler       string
+	properties       *computepb.InstanceProperties
+}
+
+type AutoscalingPolicy struct {
+	Mode              string
+	MinNumReplicas    int
+	MaxNumReplicas    int
+	UtilizationTarget float64
+}
+
+// getInstanceGroupManager returns the managed instance group which manages the instance group.
+// Unmanaged instance groups don't have it, so false is returned for them.
+func getInstanceGroupManager(ctx context.Context, projectID string, name string, locationType string, location string) (*InstanceGroupManager, bool, error) {
+	var resp *computepb.InstanceGroupManager
+	if locationType == "regions" {
+		c, err := compute.NewRegionInstanceGroupManagersRESTClient(ctx, recorder.HTTPClientOptions()...)
+		if err != nil {
+			return nil, false, err
+		}
+		defer c.Close()
+
+		resp, err = c.Get(ctx, &computepb.GetRegionInstanceGroupManagerRequest{
+			Project:              projectID,
+			Region:               location,
+			InstanceGroupManager: name,
+		})
+		if isNotFound(err) {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+	} else {
+		c, err := compute.NewInstanceGroupManagersRESTClient(ctx, recorder.HTTPClientOptions()...)
+		if err != nil {
+			return nil, false, err
+		}
+		defer c.Close()
+
+		resp, err = c.Get(ctx, &computepb.GetInstanceGroupManagerRequest{
+			Project:              projectID,
+			Zone:                 location,
+			InstanceGroupManager: name,
+		})
+		if isNotFound(err) {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	manager := &InstanceGroupManager{
+		Name:             resp.GetName(),
+		InstanceTemplate: resp.GetInstanceTemplate(),
+		TargetSize:       int(resp.GetTargetSize()),
+	}
+	if autoscaler := resp.GetStatus().GetAutoscaler(); autoscaler != "" {
+		manager.Autoscaler = path.Base(autoscaler)
+	}
+
+	if locationType == "regions" {
+		manager.Regional = true
+		manager.Region = location
+		if zones := resp.GetDistributionPolicy().GetZones(); len(zones) > 0 {
+			manager.Zone = path.Base(zones[0].GetZone())
+		}
+	} else {
+		manager.Zone = location
+		manager.Region = utils.GetRegionFromZone(location)
+	}
+
+	return manager, true, nil
+}
+
+func isNotFound(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
+
+func (m *InstanceGroupManager) GetAutoscalingPolicy(ctx context.Context, projectID string) (*AutoscalingPolicy, error) {
+	if m.Autoscaler == "" {
+		return nil, fmt.Errorf("Managed instance group: %s doesn't have an autoscaler", m.Name)
+	}
+
+	var resp *computepb.Autoscaler
+	if !m.Regional {
+		c, err := compute.NewAutoscalersRESTClient(ctx, recorder.HTTPClientOptions()...)
+		if err != nil {
+			return nil, err
+		}
+		defer c.Close()
+
+		resp, err = c.Get(ctx, &computepb.GetAutoscalerRequest{
+			Project:    projectID,
+			Zone:       m.Zone,
+			Autoscaler: m.Autoscaler,
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		c, err := compute.NewRegionAutoscalersRESTClient(ctx, recorder.HTTPClientOptions()...)
+		if err != nil {
+			return nil, err
+		}
+		defer c.Close()
+
+		resp, err = c.Get(ctx, &computepb.GetRegionAutoscalerRequest{
+			Project:    projectID,
+			Region:     m.Region,
+			Autoscaler: m.Autoscaler,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	policy := resp.GetAutoscalingPolicy()
+	return &AutoscalingPolicy{
+		Mode:              policy.GetMode(),
+		MinNumReplicas:    int(policy.GetMinNumReplicas()),
+		MaxNumReplicas:    int(policy.GetMaxNumReplicas()),
+		UtilizationTarget: policy.GetCpuUtilization().GetUtilizationTarget(),
+	}, nil
+}
+
+// getInstanceProperties returns the properties of the instance template.
+// The template is fetched once since both the cost and the service accounts depend on it.
+func (m *InstanceGroupManager) getInstanceProperties(ctx context.Context, projectID string) (*computepb.InstanceProperties, error) {
+	if m.properties != nil {
+		return m.properties, nil
+	}
+
+	// e.g. https://www.googleapis.com/compute/v1/projects/<projectID>/regions/<region>/instanceTemplates/<name>
+	project := projectOf(m.InstanceTemplate, projectID)
+	_, locationType, region, _, name := parseResourceURL(m.InstanceTemplate)
+	if locationType == "regions" {
+		resp, err := getRegionInstanceTemplate(ctx, project, region, name)
+		if err != nil {
+			return nil, err
+		}
+		m.properties = resp.GetProperties()
+
+		return m.properties, nil
+	}
+
+	c, err := compute.NewInstanceTemplatesRESTClient(ctx, recorder.HTTPClientOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	resp, err := c.Get(ctx, &computepb.GetInstanceTemplateRequest{
+		Project:          project,
+		InstanceTemplate: name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.properties = resp.GetProperties()
+
+	return m.properties, nil
+}
+
+// getRegionInstanceTemplate calls the REST API directly, since the compute client of the module
+// only has the global instance templates
+func getRegionInstanceTemplate(ctx context.Context, projectID string, region string, name string) (*computepb.InstanceTemplate, error) {
+	hc, err := recorder.HTTPClient(ctx, recorder.API_SCOPE)
+	if err != nil {
+		return nil, err
+	}
+
+	u := fmt.Sprintf("%s/projects/%s/regions/%s/instanceTemplates/%s", COMPUTE_API_ENDPOINT, projectID, region, name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := googleapi.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &computepb.InstanceTemplate{}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(body, template); err != nil {
+		return nil, fmt.Errorf("Instance template: %s is invalid: %v", name, err)
+	}
+
+	return template, nil
+}
+
+// GetMachineType returns the machine type of the instance template, e.g. e2-medium
+func (m *InstanceGroupManager) GetMachineType(ctx context.Context, projectID string) (string, error) {
+	properties, err := m.getInstanceProperties(ctx, projectID)
+	if err != nil {
+		return "", err
+	}
+
+	return path.Base(properties.GetMachineType()), nil
+}
+
+// GetServiceAccounts returns the emails of the service accounts of the instance template
+func (m *InstanceGroupManager) GetServiceAccounts(ctx context.Context, projectID string) ([]string, error) {
+	properties, err := m.getInstanceProperties(ctx, projectID)
+	if err != nil {
+		return []string{}, err
+	}
+
+	var emails []string
+	for _, serviceAccount := range properties.GetServiceAccounts() {
+		emails = append(emails, serviceAccount.GetEmail())
+	}
+
+	return emails, nil
+}
+
+// GetNetworkReferences returns the VPC networks of the instance template which the instances reach databases through
+func (m *InstanceGroupManager) GetNetworkReferences(ctx context.Context, projectID string) ([]string, error) {
+	properties, err := m.getInstanceProperties(ctx, projectID)
+	if err != nil {
+		return []string{}, err
+	}
+
+	var references []string
+	for _, network := range properties.GetNetworkInterfaces() {
+		references = append(references, fmt.Sprintf("network:%s", path.Base(network.GetNetwork())))
+	}
+
+	return references, nil
+}
+
+// GetExpectedReplicas returns how many instances the group is expected to run.
+// Groups without an active autoscaler keep their target size.
+func (m *InstanceGroupManager) GetExpectedReplicas(ctx context.Context, projectID string) (float64, error) {
+	if m.Autoscaler == "" {
+		return float64(m.TargetSize), nil
+	}
+
+	policy, err := m.GetAutoscalingPolicy(ctx, projectID)
+	if err != nil {
+		return 0, err
+	}
+
+	if policy.Mode == "OFF" {
+		return float64(m.TargetSize), nil
+	}
+
+	model := cost.GetReplicaModel()
+	replicas := cost.ExpectedReplicas(model, policy.MinNumReplicas, policy.MaxNumReplicas, policy.UtilizationTarget)
+	log.Printf("Autoscaler of %s: min %d, max %d, target utilization %.2f => %.2f replicas (%s model)", m.Name, policy.MinNumReplicas, policy.MaxNumReplicas, policy.UtilizationTarget, replicas, model)
+
+	return replicas, nil
+}
+
+// GetExpectedCost returns the cost of the whole group based on the expected replicas
+// instead of the instances running at the moment
+func (m *InstanceGroupManager) GetExpectedCost(ctx context.Context, projectID string) (float64, error) {
+	machineType, err := m.GetMachineType(ctx, projectID)
+	if err != nil {
+		return 0, err
+	}
+
+	machineTypeCost, err := computeengine.GetMachineTypeCost(ctx, projectID, m.Zone, machineType)
+	if err != nil {
+		return 0, err
+	}
+
+	replicas, err := m.GetExpectedReplicas(ctx, projectID)
+	if err != nil {
+		return 0, err
+	}
+
+	return machineTypeCost * replicas, nil
+}
+
+// applyGroupCost spreads the expected cost of a managed instance group over its current instances
+func applyGroupCost(ctx context.Context, projectID string, name string, locationType string, location string, instances []*Instance) ([]*Instance, error) {
+	manager, ok, err := getInstanceGroupManager(ctx, projectID, name, locationType, location)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok || len(instances) == 0 {
+		return instances, nil
+	}
+
+	if manager.Zone == "" {
+		manager.Zone = instances[0].Zone
+	}
+
+	groupCost, err := manager.GetExpectedCost(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceAccounts, err := manager.GetServiceAccounts(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	references, err := manager.GetNetworkReferences(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, instance := range instances {
+		instance.Managed = true
+		instance.Cost = groupCost / float64(len(instances))
+		instance.ServiceAccounts = serviceAccounts
+		instance.References = references
+	}
+
+	return instances, nil
+}
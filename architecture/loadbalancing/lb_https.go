@@ -5,130 +5,1184 @@ import (
 	"fmt"
 	"log"
 	"path"
+	"sort"
 	"strings"
+	"sync"
 
 	compute "cloud.google.com/go/compute/apiv1"
 	computing "github.com/mittz/roleplay-webapp-assess/architecture/computing"
+	"github.com/mittz/roleplay-webapp-assess/architecture/computing/appengine"
+	"github.com/mittz/roleplay-webapp-assess/architecture/computing/cloudfunctions"
 	"github.com/mittz/roleplay-webapp-assess/architecture/computing/cloudrun"
 	"github.com/mittz/roleplay-webapp-assess/architecture/computing/computeengine"
+	"github.com/mittz/roleplay-webapp-assess/architecture/inventory"
+	"github.com/mittz/roleplay-webapp-assess/architecture/storage"
+	"github.com/mittz/roleplay-webapp-assess/architecture/storage/cloudstorage"
+	"github.com/mittz/roleplay-webapp-assess/recorder"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/api/iterator"
 	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// Maximum number of the concurrent lookups of the backends
+	DISCOVERY_PARALLELISM = 4
 )
 
 type LoadBalancingHTTPS struct {
-	id       string
-	backends []computing.Computing
+	id                string
+	region            string
+	scheme            string
+	targetProxy       string
+	urlMap            string
+	routes            []Route
+	backends          []computing.Computing
+	buckets           []storage.Storage
+	externalEndpoints []*ExternalEndpoint
+	cdnEnabled        bool
+	httpsEnabled      bool
+	// Instances which are stopped or fail the health checks of every backend service
+	unhealthyInstances []*Instance
+	// Projects of the backends by their IDs, which can differ from the project of the forwarding rule
+	owners map[string]string
+}
+
+// Route is a backend service or a backend bucket of the URL map with the resources behind it
+type Route struct {
+	Name              string
+	Backends          []string // IDs of the computing backends
+	Buckets           []string
+	ExternalEndpoints []string
+}
+
+type ForwardingRule struct {
+	Name                string
+	Region              string
+	Target              string
+	TargetPool          string
+	LoadBalancingScheme string
+}
+
+type TargetHTTPProxy struct {
+	Name       string
+	URLMap     string
+	URLRegion  string
+	URLProject string
+}
+
+type URLMap struct {
+	Name            string
+	Project         string
+	Region          string
+	DefaultService  string
+	BackendServices []string // Self-links, since the backends can be in other projects than the URL map
+	BackendBuckets  []string // Self-links
+}
+
+type BackendService struct {
+	Name      string
+	Project   string
+	Region    string
+	Backends  []*computepb.Backend
+	EnableCDN bool
+	CacheMode string
+
+	mu                    sync.Mutex
+	networkEndpointGroups map[string]*computepb.NetworkEndpointGroup
+}
+
+type BackendBucket struct {
+	Name       string
+	BucketName string
+	EnableCDN  bool
+	CacheMode  string
+}
+
+type Instance struct {
+	Name    string
+	Project string
+	Zone    string
+	Status  string
+	Managed bool
+	Cost    float64
+	// Health state of the instance in the backend service, e.g. HEALTHY. Empty when it's unknown.
+	Health string
+	// Service accounts of the instance template of the managed instance group
+	ServiceAccounts []string
+	// Database references of the instance template of the managed instance group, e.g. network:default
+	References []string
+}
+
+type ExternalEndpoint struct {
+	Group    string
+	Type     string
+	Address  string
+	Internet bool
+}
+
+type Serverless struct {
+	Name    string
+	Project string
+	Region  string
+	Service string
+	Version string
+}
+
+func GetLoadBalancingHTTPS(ctx context.Context, projectID string, hostIP string) (LoadBalancingHTTPS, bool) {
+	forwardingRules, err := listForwardingRules(ctx, projectID, hostIP)
+	if err != nil {
+		log.Printf("GetLoadBalancingHTTPS - listForwardingRules: %v", err)
+		return LoadBalancingHTTPS{}, false
+	}
+	// HTTP is often served by another forwarding rule to redirect to HTTPS
+	httpsEnabled := false
+	for _, rule := range forwardingRules {
+		if rule.IsHTTPS() {
+			httpsEnabled = true
+		}
+	}
+
+	forwardingRule, targetHTTPProxy, urlMap, err := selectForwardingRule(ctx, projectID, forwardingRules)
+	if err != nil {
+		log.Printf("GetLoadBalancingHTTPS - selectForwardingRule: %v", err)
+		return LoadBalancingHTTPS{}, false
+	}
+
+	backendServices, err := urlMap.ListBackendServices(ctx)
+	if err != nil {
+		log.Printf("GetLoadBalancingHTTPS - ListBackendServices: %v", err)
+		return LoadBalancingHTTPS{}, false
+	}
+
+	backendBuckets, err := urlMap.ListBackendBuckets(ctx)
+	if err != nil {
+		log.Printf("GetLoadBalancingHTTPS - ListBackendBuckets: %v", err)
+		return LoadBalancingHTTPS{}, false
+	}
+
+	// The backends of the backend services are listed concurrently
+	type backendServiceResult struct {
+		instances         []*Instance
+		serverlesses      []*Serverless
+		externalEndpoints []*ExternalEndpoint
+	}
+	results := make([]backendServiceResult, len(backendServices))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(DISCOVERY_PARALLELISM)
+	for i, backendService := range backendServices {
+		i, backendService := i, backendService
+		g.Go(func() error {
+			x, err := backendService.ListInstances(gctx)
+			if err != nil {
+				return fmt.Errorf("backendService.ListInstances: %w", err)
+			}
+
+			y, err := backendService.ListServerlesses(gctx)
+			if err != nil {
+				return fmt.Errorf("backendService.ListServerlesses: %w", err)
+			}
+
+			z, err := backendService.ListExternalEndpoints(gctx)
+			if err != nil {
+				return fmt.Errorf("backendService.ListExternalEndpoints: %w", err)
+			}
+
+			results[i] = backendServiceResult{instances: x, serverlesses: y, externalEndpoints: z}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		log.Printf("GetLoadBalancingHTTPS - %v", err)
+		return LoadBalancingHTTPS{}, false
+	}
+
+	cdnEnabled := false
+	var instances []*Instance
+	var serverlesses []*Serverless
+	var externalEndpoints []*ExternalEndpoint
+	var routes []Route
+	routeKeys := make(map[string][]string)
+	for i, backendService := range backendServices {
+		if backendService.EnableCDN {
+			cdnEnabled = true
+			log.Printf("Cloud CDN is enabled on backend service: %s (Cache mode: %s)", backendService.Name, backendService.CacheMode)
+		}
+
+		instances = append(instances, results[i].instances...)
+		for _, instance := range results[i].instances {
+			routeKeys[backendService.Name] = append(routeKeys[backendService.Name], instance.key())
+		}
+
+		serverlesses = append(serverlesses, results[i].serverlesses...)
+		for _, serverless := range results[i].serverlesses {
+			routeKeys[backendService.Name] = append(routeKeys[backendService.Name], serverless.key())
+		}
+
+		externalEndpoints = append(externalEndpoints, results[i].externalEndpoints...)
+		route := Route{Name: backendService.Name}
+		for _, endpoint := range results[i].externalEndpoints {
+			route.ExternalEndpoints = append(route.ExternalEndpoints, endpoint.Address)
+		}
+		routes = append(routes, route)
+	}
+
+	// An instance counts as long as it serves any of the backend services
+	serving := make(map[string]bool)
+	for _, instance := range instances {
+		serving[instance.key()] = serving[instance.key()] || instance.IsServing()
+	}
+
+	var unhealthyInstances []*Instance
+	for _, instance := range instances {
+		if serving[instance.key()] {
+			continue
+		}
+		// Report each instance once
+		serving[instance.key()] = true
+		unhealthyInstances = append(unhealthyInstances, instance)
+		log.Printf("Unhealthy backend was found: %s (Zone: %s, Status: %s, Health: %s)", instance.Name, instance.Zone, instance.Status, instance.Health)
+	}
+
+	// The same backend can be referenced by multiple backend services
+	type backendResolver func(ctx context.Context) (computing.Computing, error)
+	seen := make(map[string]bool)
+	var keys, projects []string
+	var resolvers []backendResolver
+	for _, x := range instances {
+		x := x
+		if seen[x.key()] {
+			continue
+		}
+		seen[x.key()] = true
+		keys = append(keys, x.key())
+		projects = append(projects, x.Project)
+		resolvers = append(resolvers, func(ctx context.Context) (computing.Computing, error) {
+			b, err := x.GetComputeEngine(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("x.GetComputeEngine: %w", err)
+			}
+			return b, nil
+		})
+	}
+
+	for _, serverless := range serverlesses {
+		serverless := serverless
+		if seen[serverless.key()] {
+			continue
+		}
+		seen[serverless.key()] = true
+		keys = append(keys, serverless.key())
+		projects = append(projects, serverless.Project)
+		resolvers = append(resolvers, func(ctx context.Context) (computing.Computing, error) {
+			b, err := serverless.Get(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("serverless.Get: %w", err)
+			}
+			return b, nil
+		})
+	}
+
+	backends := make([]computing.Computing, len(resolvers))
+	g, gctx = errgroup.WithContext(ctx)
+	g.SetLimit(DISCOVERY_PARALLELISM)
+	for i, resolve := range resolvers {
+		i, resolve := i, resolve
+		g.Go(func() error {
+			b, err := resolve(gctx)
+			if err != nil {
+				return err
+			}
+			backends[i] = b
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		log.Printf("GetLoadBalancingHTTPS - %v", err)
+		return LoadBalancingHTTPS{}, false
+	}
+
+	backendIDs := make(map[string]string)
+	owners := make(map[string]string)
+	for i, key := range keys {
+		backendIDs[key] = backends[i].GetID()
+		owners[backends[i].GetID()] = projects[i]
+	}
+
+	for i, route := range routes {
+		for _, key := range routeKeys[route.Name] {
+			routes[i].Backends = append(routes[i].Backends, backendIDs[key])
+		}
+	}
+
+	var buckets []storage.Storage
+	for _, backendBucket := range backendBuckets {
+		if backendBucket.EnableCDN {
+			cdnEnabled = true
+			log.Printf("Cloud CDN is enabled on backend bucket: %s (Cache mode: %s)", backendBucket.Name, backendBucket.CacheMode)
+		}
+
+		b, err := backendBucket.GetCloudStorage(ctx)
+		if err != nil {
+			log.Printf("GetLoadBalancingHTTPS - backendBucket.GetCloudStorage: %v", err)
+			return LoadBalancingHTTPS{}, false
+		}
+
+		buckets = append(buckets, b)
+		routes = append(routes, Route{Name: backendBucket.Name, Buckets: []string{b.GetID()}})
+	}
+
+	// Regional load balancers have regional forwarding rules and URL maps.
+	// Cross-region internal load balancers are global even though they are internal.
+	region := urlMap.Region
+	if region == "" && forwardingRule.Region != "" {
+		region = forwardingRule.Region
+	}
+
+	return LoadBalancingHTTPS{
+		id:                 forwardingRule.Name,
+		region:             region,
+		scheme:             forwardingRule.LoadBalancingScheme,
+		targetProxy:        targetHTTPProxy.Name,
+		urlMap:             urlMap.Name,
+		routes:             routes,
+		backends:           backends,
+		buckets:            buckets,
+		owners:             owners,
+		externalEndpoints:  externalEndpoints,
+		cdnEnabled:         cdnEnabled,
+		httpsEnabled:       httpsEnabled,
+		unhealthyInstances: unhealthyInstances,
+	}, true
+}
+
+// listForwardingRules returns the forwarding rules of the IP address, e.g. for HTTP and HTTPS.
+// The product API is called when the snapshot doesn't have them, e.g. just created.
+func listForwardingRules(ctx context.Context, projectID string, hostIP string) ([]*ForwardingRule, error) {
+	var rules []*computepb.ForwardingRule
+	if inv, ok := inventory.Get(projectID); ok {
+		rules = inv.ListForwardingRules()
+	}
+
+	x := filterForwardingRules(rules, hostIP)
+	if len(x) == 0 {
+		rules, err := listForwardingRulesFromAPI(ctx, projectID)
+		if err != nil {
+			return nil, err
+		}
+		x = filterForwardingRules(rules, hostIP)
+	}
+
+	if len(x) == 0 {
+		return nil, fmt.Errorf("None forwarding rule matched to the host ipaddress")
+	}
+
+	return x, nil
+}
+
+func filterForwardingRules(rules []*computepb.ForwardingRule, hostIP string) []*ForwardingRule {
+	var x []*ForwardingRule
+	for _, rule := range rules {
+		if rule.GetIPAddress() == hostIP {
+			x = append(x, newForwardingRule(rule))
+		}
+	}
+
+	return x
+}
+
+// listForwardingRulesFromAPI is replaced in the tests to resolve without the product API
+var listForwardingRulesFromAPI = func(ctx context.Context, projectID string) ([]*computepb.ForwardingRule, error) {
+	c, err := compute.NewForwardingRulesRESTClient(ctx, recorder.HTTPClientOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	req := &computepb.AggregatedListForwardingRulesRequest{
+		Project: projectID,
+	}
+	var rules []*computepb.ForwardingRule
+	it := c.AggregatedList(ctx, req)
+	for {
+		resp, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, resp.Value.GetForwardingRules()...)
+	}
+
+	return rules, nil
+}
+
+// selectForwardingRule returns the forwarding rule which serves the app with its proxy and URL map.
+// The HTTPS rules come first, and a rule whose URL map has no backend, e.g. the HTTP to HTTPS redirect, is the last resort.
+func selectForwardingRule(ctx context.Context, projectID string, forwardingRules []*ForwardingRule) (*ForwardingRule, *TargetHTTPProxy, *URLMap, error) {
+	rules := make([]*ForwardingRule, len(forwardingRules))
+	copy(rules, forwardingRules)
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].IsHTTPS() && !rules[j].IsHTTPS()
+	})
+
+	var selected *ForwardingRule
+	var selectedProxy *TargetHTTPProxy
+	var selectedURLMap *URLMap
+	var lastErr error
+	for _, rule := range rules {
+		targetHTTPProxy, err := rule.GetTargetHttpProxy(ctx, projectID)
+		if err != nil {
+			lastErr = fmt.Errorf("GetTargetHttpProxy (%s): %v", rule.Name, err)
+			continue
+		}
+
+		urlMap, err := targetHTTPProxy.GetURLMap(ctx, projectID)
+		if err != nil {
+			lastErr = fmt.Errorf("GetURLMap (%s): %v", rule.Name, err)
+			continue
+		}
+
+		if len(urlMap.BackendServices) > 0 || len(urlMap.BackendBuckets) > 0 {
+			return rule, targetHTTPProxy, urlMap, nil
+		}
+
+		if selected == nil {
+			selected, selectedProxy, selectedURLMap = rule, targetHTTPProxy, urlMap
+		}
+	}
+
+	if selected == nil {
+		return nil, nil, nil, lastErr
+	}
+
+	return selected, selectedProxy, selectedURLMap, nil
+}
+
+// IsHTTPS returns true when the target of the forwarding rule is an HTTPS proxy
+func (f *ForwardingRule) IsHTTPS() bool {
+	_, _, _, targetType, _ := parseResourceURL(f.Target)
+	return targetType == "targetHttpsProxies"
+}
+
+func newForwardingRule(rule *computepb.ForwardingRule) *ForwardingRule {
+	region := ""
+	if rule.GetRegion() != "" {
+		region = path.Base(rule.GetRegion())
+	}
+
+	return &ForwardingRule{
+		Name:                rule.GetName(),
+		Region:              region,
+		Target:              rule.GetTarget(),
+		TargetPool:          path.Base(rule.GetTarget()),
+		LoadBalancingScheme: rule.GetLoadBalancingScheme(),
+	}
 }
 
-type ForwardingRule struct {
-	Name       string
-	Region     string
-	TargetPool string
-}
+// GetTargetHttpProxy resolves the target of the forwarding rule.
+// The target can be an HTTP or HTTPS proxy, either global or regional
+// e.g. https://www.googleapis.com/compute/v1/projects/<projectID>/regions/<region>/targetHttpsProxies/<name>
+func (f *ForwardingRule) GetTargetHttpProxy(ctx context.Context, projectID string) (*TargetHTTPProxy, error) {
+	project := projectOf(f.Target, projectID)
+	_, locationType, region, targetType, name := parseResourceURL(f.Target)
+
+	var urlMap string
+	httpProxy, httpsProxy := &computepb.TargetHttpProxy{}, &computepb.TargetHttpsProxy{}
+	switch {
+	case targetType == "targetHttpProxies" && getFromInventory(project, inventory.ASSET_TARGET_HTTP_PROXY, f.Target, httpProxy):
+		urlMap = httpProxy.GetUrlMap()
+	case targetType == "targetHttpsProxies" && getFromInventory(project, inventory.ASSET_TARGET_HTTPS_PROXY, f.Target, httpsProxy):
+		urlMap = httpsProxy.GetUrlMap()
+	case targetType == "targetHttpProxies" && locationType == "regions":
+		c, err := compute.NewRegionTargetHttpProxiesRESTClient(ctx, recorder.HTTPClientOptions()...)
+		if err != nil {
+			return nil, err
+		}
+		defer c.Close()
+
+		resp, err := c.Get(ctx, &computepb.GetRegionTargetHttpProxyRequest{
+			Project:         project,
+			Region:          region,
+			TargetHttpProxy: name,
+		})
+		if err != nil {
+			return nil, err
+		}
+		urlMap = resp.GetUrlMap()
+	case targetType == "targetHttpsProxies" && locationType == "regions":
+		c, err := compute.NewRegionTargetHttpsProxiesRESTClient(ctx, recorder.HTTPClientOptions()...)
+		if err != nil {
+			return nil, err
+		}
+		defer c.Close()
+
+		resp, err := c.Get(ctx, &computepb.GetRegionTargetHttpsProxyRequest{
+			Project:          project,
+			Region:           region,
+			TargetHttpsProxy: name,
+		})
+		if err != nil {
+			return nil, err
+		}
+		urlMap = resp.GetUrlMap()
+	case targetType == "targetHttpsProxies":
+		c, err := compute.NewTargetHttpsProxiesRESTClient(ctx, recorder.HTTPClientOptions()...)
+		if err != nil {
+			return nil, err
+		}
+		defer c.Close()
+
+		resp, err := c.Get(ctx, &computepb.GetTargetHttpsProxyRequest{
+			Project:          project,
+			TargetHttpsProxy: name,
+		})
+		if err != nil {
+			return nil, err
+		}
+		urlMap = resp.GetUrlMap()
+	case targetType == "targetHttpProxies":
+		c, err := compute.NewTargetHttpProxiesRESTClient(ctx, recorder.HTTPClientOptions()...)
+		if err != nil {
+			return nil, err
+		}
+		defer c.Close()
+
+		resp, err := c.Get(ctx, &computepb.GetTargetHttpProxyRequest{
+			Project:         project,
+			TargetHttpProxy: name,
+		})
+		if err != nil {
+			return nil, err
+		}
+		urlMap = resp.GetUrlMap()
+	default:
+		return nil, fmt.Errorf("Target: %s of the forwarding rule: %s is not supported", f.Target, f.Name)
+	}
+
+	_, urlMapLocationType, urlMapRegion, _, _ := parseResourceURL(urlMap)
+	if urlMapLocationType != "regions" {
+		urlMapRegion = ""
+	}
+
+	return &TargetHTTPProxy{
+		Name:       name,
+		URLMap:     path.Base(urlMap),
+		URLRegion:  urlMapRegion,
+		URLProject: projectOf(urlMap, project),
+	}, nil
+}
+
+func (t *TargetHTTPProxy) GetURLMap(ctx context.Context, projectID string) (*URLMap, error) {
+	project := t.URLProject
+	if project == "" {
+		project = projectID
+	}
+
+	resp := &computepb.UrlMap{}
+	location := "global"
+	if t.URLRegion != "" {
+		location = fmt.Sprintf("regions/%s", t.URLRegion)
+	}
+	switch {
+	case getFromInventory(project, inventory.ASSET_URL_MAP, fmt.Sprintf("projects/%s/%s/urlMaps/%s", project, location, t.URLMap), resp):
+		// The snapshot has the URL map
+	case t.URLRegion != "":
+		c, err := compute.NewRegionUrlMapsRESTClient(ctx, recorder.HTTPClientOptions()...)
+		if err != nil {
+			return nil, err
+		}
+		defer c.Close()
+
+		req := &computepb.GetRegionUrlMapRequest{
+			Project: project,
+			Region:  t.URLRegion,
+			UrlMap:  t.URLMap,
+		}
+		resp, err = c.Get(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		c, err := compute.NewUrlMapsRESTClient(ctx, recorder.HTTPClientOptions()...)
+		if err != nil {
+			return nil, err
+		}
+		defer c.Close()
+
+		req := &computepb.GetUrlMapRequest{
+			Project: project,
+			UrlMap:  t.URLMap,
+		}
+		resp, err = c.Get(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	urlMap := &URLMap{
+		Name:           resp.GetName(),
+		Project:        project,
+		Region:         t.URLRegion,
+		DefaultService: path.Base(resp.GetDefaultService()),
+	}
+
+	// Walk every place where a URL map can reference a backend:
+	// the default service, host rules -> path matchers -> path rules and route rules
+	urlMap.addService(resp.GetDefaultService())
+	urlMap.addRouteAction(resp.GetDefaultRouteAction())
+
+	pathMatchers := make(map[string]*computepb.PathMatcher)
+	for _, pathMatcher := range resp.GetPathMatchers() {
+		pathMatchers[pathMatcher.GetName()] = pathMatcher
+	}
+
+	// Path matchers which no host rule refers to never receive traffic
+	visited := make(map[string]bool)
+	for _, hostRule := range resp.GetHostRules() {
+		if visited[hostRule.GetPathMatcher()] {
+			continue
+		}
+		visited[hostRule.GetPathMatcher()] = true
+
+		pathMatcher, ok := pathMatchers[hostRule.GetPathMatcher()]
+		if !ok {
+			return nil, fmt.Errorf("Path matcher: %s referenced by hosts %v was not found in %s", hostRule.GetPathMatcher(), hostRule.GetHosts(), resp.GetName())
+		}
+
+		urlMap.addService(pathMatcher.GetDefaultService())
+		urlMap.addRouteAction(pathMatcher.GetDefaultRouteAction())
+
+		for _, pathRule := range pathMatcher.GetPathRules() {
+			urlMap.addService(pathRule.GetService())
+			urlMap.addRouteAction(pathRule.GetRouteAction())
+		}
+
+		for _, routeRule := range pathMatcher.GetRouteRules() {
+			urlMap.addService(routeRule.GetService())
+			urlMap.addRouteAction(routeRule.GetRouteAction())
+		}
+	}
+
+	return urlMap, nil
+}
+
+// addService records a backend service or backend bucket URL once.
+// The whole URL is kept since the backend can be in another project than the URL map.
+// e.g. https://www.googleapis.com/compute/v1/projects/<projectID>/global/backendBuckets/<name>
+func (u *URLMap) addService(service string) {
+	if service == "" {
+		return
+	}
+
+	if _, _, _, resourceType, _ := parseResourceURL(service); resourceType == "backendBuckets" {
+		for _, x := range u.BackendBuckets {
+			if x == service {
+				return
+			}
+		}
+		u.BackendBuckets = append(u.BackendBuckets, service)
+		return
+	}
+
+	for _, x := range u.BackendServices {
+		if x == service {
+			return
+		}
+	}
+	u.BackendServices = append(u.BackendServices, service)
+}
+
+func (u *URLMap) addRouteAction(routeAction *computepb.HttpRouteAction) {
+	for _, weightedBackendService := range routeAction.GetWeightedBackendServices() {
+		if weightedBackendService.GetWeight() == 0 {
+			continue
+		}
+		u.addService(weightedBackendService.GetBackendService())
+	}
+}
+
+func (u *URLMap) ListBackendServices(ctx context.Context) ([]*BackendService, error) {
+	if u.Region != "" {
+		return u.listRegionBackendServices(ctx)
+	}
+
+	c, err := compute.NewBackendServicesRESTClient(ctx, recorder.HTTPClientOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	var backendServices []*BackendService
+	for _, service := range u.BackendServices {
+		project, name := projectOf(service, u.Project), path.Base(service)
+		x := &computepb.BackendService{}
+		if getFromInventory(project, inventory.ASSET_BACKEND_SERVICE, fmt.Sprintf("projects/%s/global/backendServices/%s", project, name), x) {
+			backendServices = append(backendServices, newBackendService(project, x))
+			continue
+		}
+
+		req := &computepb.GetBackendServiceRequest{
+			Project:        project,
+			BackendService: name,
+		}
+		resp, err := c.Get(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		backendServices = append(backendServices, newBackendService(project, resp))
+	}
+
+	return backendServices, nil
+}
+
+// Regional URL maps refer to regional backend services in the same region
+func (u *URLMap) listRegionBackendServices(ctx context.Context) ([]*BackendService, error) {
+	c, err := compute.NewRegionBackendServicesRESTClient(ctx, recorder.HTTPClientOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	var backendServices []*BackendService
+	for _, service := range u.BackendServices {
+		project, name := projectOf(service, u.Project), path.Base(service)
+		x := &computepb.BackendService{}
+		if getFromInventory(project, inventory.ASSET_BACKEND_SERVICE, fmt.Sprintf("projects/%s/regions/%s/backendServices/%s", project, u.Region, name), x) {
+			backendServices = append(backendServices, newBackendService(project, x))
+			continue
+		}
+
+		req := &computepb.GetRegionBackendServiceRequest{
+			Project:        project,
+			Region:         u.Region,
+			BackendService: name,
+		}
+		resp, err := c.Get(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		backendServices = append(backendServices, newBackendService(project, resp))
+	}
+
+	return backendServices, nil
+}
+
+func newBackendService(project string, resp *computepb.BackendService) *BackendService {
+	region := ""
+	if resp.GetRegion() != "" {
+		region = path.Base(resp.GetRegion())
+	}
+
+	return &BackendService{
+		Name:      resp.GetName(),
+		Project:   project,
+		Region:    region,
+		Backends:  resp.GetBackends(),
+		EnableCDN: resp.GetEnableCDN(),
+		CacheMode: resp.GetCdnPolicy().GetCacheMode(),
+	}
+}
+
+func (u *URLMap) ListBackendBuckets(ctx context.Context) ([]*BackendBucket, error) {
+	if len(u.BackendBuckets) == 0 {
+		return nil, nil
+	}
+
+	c, err := compute.NewBackendBucketsRESTClient(ctx, recorder.HTTPClientOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	var backendBuckets []*BackendBucket
+	for _, bucket := range u.BackendBuckets {
+		req := &computepb.GetBackendBucketRequest{
+			Project:       projectOf(bucket, u.Project),
+			BackendBucket: path.Base(bucket),
+		}
+		resp, err := c.Get(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		backendBuckets = append(backendBuckets, &BackendBucket{
+			Name:       resp.GetName(),
+			BucketName: resp.GetBucketName(),
+			EnableCDN:  resp.GetEnableCdn(),
+			CacheMode:  resp.GetCdnPolicy().GetCacheMode(),
+		})
+	}
+
+	return backendBuckets, nil
+}
+
+// parseResourceURL splits a resource URL into its project, location, type and name.
+// The project is empty when the URL is only a name.
+// e.g. https://www.googleapis.com/compute/v1/projects/<projectID>/zones/<zone>/networkEndpointGroups/<name>
+// e.g. https://www.googleapis.com/compute/v1/projects/<projectID>/global/networkEndpointGroups/<name>
+func parseResourceURL(resource string) (project string, locationType string, location string, resourceType string, name string) {
+	names := strings.Split(resource, "/")
+	for i, x := range names {
+		if x != "projects" || i+2 >= len(names) {
+			continue
+		}
+
+		if names[i+2] == "global" && i+4 < len(names) {
+			return names[i+1], names[i+2], "", names[i+3], names[i+4]
+		}
+
+		if i+5 < len(names) {
+			return names[i+1], names[i+2], names[i+3], names[i+4], names[i+5]
+		}
+	}
+
+	return "", "", "", "", path.Base(resource)
+}
+
+// projectOf returns the project which owns the resource, or the given project when the URL doesn't have one.
+// With Shared VPC the backends can be in service projects rather than the project of the forwarding rule.
+func projectOf(resource string, projectID string) string {
+	if project, _, _, _, _ := parseResourceURL(resource); project != "" {
+		return project
+	}
+
+	return projectID
+}
+
+// getFromInventory looks up the resource in the snapshot of the project by its URL or path.
+// False is returned when the snapshot isn't loaded or doesn't have it, so the product API is called instead.
+func getFromInventory(projectID string, assetType string, resource string, m proto.Message) bool {
+	inv, ok := inventory.Get(projectID)
+	if !ok {
+		return false
+	}
+
+	// e.g. https://www.googleapis.com/compute/v1/projects/<projectID>/global/urlMaps/<name>
+	if i := strings.Index(resource, "projects/"); i >= 0 {
+		resource = resource[i:]
+	}
+
+	return inv.GetComputeResource(assetType, resource, m)
+}
+
+// getNetworkEndpointGroup returns the NEG of the backend group.
+// Both the instances and the external endpoints are resolved from a NEG, so it's fetched once.
+func (b *BackendService) getNetworkEndpointGroup(ctx context.Context, group string) (*computepb.NetworkEndpointGroup, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if neg, ok := b.networkEndpointGroups[group]; ok {
+		return neg, nil
+	}
+
+	project := projectOf(group, b.Project)
+	_, locationType, location, _, name := parseResourceURL(group)
+	neg := &computepb.NetworkEndpointGroup{}
+	if !getFromInventory(project, inventory.ASSET_NETWORK_ENDPOINT_GROUP, group, neg) {
+		var err error
+		switch locationType {
+		case "zones":
+			neg, err = getZoneNetworkEndpointGroup(ctx, project, name, location)
+		case "regions":
+			neg, err = getRegionNetworkEndpointGroup(ctx, project, name, location)
+		default:
+			neg, err = getGlobalNetworkEndpointGroup(ctx, project, name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if b.networkEndpointGroups == nil {
+		b.networkEndpointGroups = make(map[string]*computepb.NetworkEndpointGroup)
+	}
+	b.networkEndpointGroups[group] = neg
+
+	return neg, nil
+}
+
+func getZoneNetworkEndpointGroup(ctx context.Context, projectID string, name string, zone string) (*computepb.NetworkEndpointGroup, error) {
+	c, err := compute.NewNetworkEndpointGroupsRESTClient(ctx, recorder.HTTPClientOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	return c.Get(ctx, &computepb.GetNetworkEndpointGroupRequest{
+		Project:              projectID,
+		NetworkEndpointGroup: name,
+		Zone:                 zone,
+	})
+}
+
+func getRegionNetworkEndpointGroup(ctx context.Context, projectID string, name string, region string) (*computepb.NetworkEndpointGroup, error) {
+	c, err := compute.NewRegionNetworkEndpointGroupsRESTClient(ctx, recorder.HTTPClientOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	return c.Get(ctx, &computepb.GetRegionNetworkEndpointGroupRequest{
+		Project:              projectID,
+		NetworkEndpointGroup: name,
+		Region:               region,
+	})
+}
+
+func getGlobalNetworkEndpointGroup(ctx context.Context, projectID string, name string) (*computepb.NetworkEndpointGroup, error) {
+	c, err := compute.NewGlobalNetworkEndpointGroupsRESTClient(ctx, recorder.HTTPClientOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	return c.Get(ctx, &computepb.GetGlobalNetworkEndpointGroupRequest{
+		Project:              projectID,
+		NetworkEndpointGroup: name,
+	})
+}
+
+// ListInstances returns the VMs behind the backend service.
+// Each backend group is resolved in its own project, e.g. a service project of Shared VPC.
+func (b *BackendService) ListInstances(ctx context.Context) ([]*Instance, error) {
+	var instances []*Instance
+	for _, backend := range b.Backends {
+		project := projectOf(backend.GetGroup(), b.Project)
+		_, locationType, location, groupType, name := parseResourceURL(backend.GetGroup())
+
+		if groupType == "networkEndpointGroups" && locationType == "zones" {
+			neg, err := b.getNetworkEndpointGroup(ctx, backend.GetGroup())
+			if err != nil {
+				return nil, err
+			}
+
+			x, err := getZoneNetworkEndpointGroupInstances(ctx, project, neg, location)
+			if err != nil {
+				return nil, err
+			}
+
+			b.applyHealth(ctx, backend.GetGroup(), x)
+			instances = append(instances, x...)
+			continue
+		}
+
+		if groupType != "instanceGroups" {
+			continue
+		}
+
+		if locationType == "regions" {
+			x, err := getRegionInstanceGroup(ctx, project, name, location)
+			if err != nil {
+				return nil, err
+			}
+
+			x, err = applyGroupCost(ctx, project, name, locationType, location, x)
+			if err != nil {
+				return nil, err
+			}
+
+			b.applyHealth(ctx, backend.GetGroup(), x)
+			instances = append(instances, x...)
+		}
+
+		if locationType == "zones" {
+			x, err := getZoneInstanceGroup(ctx, project, name, location)
+			if err != nil {
+				return nil, err
+			}
+
+			x, err = applyGroupCost(ctx, project, name, locationType, location, x)
+			if err != nil {
+				return nil, err
+			}
 
-type TargetHTTPProxy struct {
-	Name   string
-	URLMap string
-}
+			b.applyHealth(ctx, backend.GetGroup(), x)
+			instances = append(instances, x...)
+		}
+	}
 
-type URLMap struct {
-	Name           string
-	DefaultService string
+	return instances, nil
 }
 
-type BackendService struct {
-	Name     string
-	Backends []*computepb.Backend
-}
+// getHealth returns the health state of the instances of the backend group by their zones and names.
+// An instance serving multiple ports is healthy if any of them is healthy.
+func (b *BackendService) getHealth(ctx context.Context, group string) (map[string]string, error) {
+	reference := &computepb.ResourceGroupReference{Group: &group}
 
-type Instance struct {
-	Name   string
-	Zone   string
-	Status string
-}
+	var resp *computepb.BackendServiceGroupHealth
+	if b.Region != "" {
+		c, err := compute.NewRegionBackendServicesRESTClient(ctx, recorder.HTTPClientOptions()...)
+		if err != nil {
+			return nil, err
+		}
+		defer c.Close()
 
-type Serverless struct {
-	Name    string
-	Region  string
-	Service string
-}
+		resp, err = c.GetHealth(ctx, &computepb.GetHealthRegionBackendServiceRequest{
+			Project:                        b.Project,
+			Region:                         b.Region,
+			BackendService:                 b.Name,
+			ResourceGroupReferenceResource: reference,
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		c, err := compute.NewBackendServicesRESTClient(ctx, recorder.HTTPClientOptions()...)
+		if err != nil {
+			return nil, err
+		}
+		defer c.Close()
 
-func GetLoadBalancingHTTPS(projectID string, hostIP string) (LoadBalancingHTTPS, bool) {
-	forwardingRule, err := getForwardingRule(projectID, hostIP)
-	if err != nil {
-		log.Printf("GetLoadBalancingHTTPS - getForwardingRule: %v", err)
-		return LoadBalancingHTTPS{}, false
+		resp, err = c.GetHealth(ctx, &computepb.GetHealthBackendServiceRequest{
+			Project:                        b.Project,
+			BackendService:                 b.Name,
+			ResourceGroupReferenceResource: reference,
+		})
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	targetHTTPProxy, err := forwardingRule.GetTargetHttpProxy(projectID)
-	if err != nil {
-		log.Printf("GetLoadBalancingHTTPS - GetTargetHttpProxy: %v", err)
-		return LoadBalancingHTTPS{}, false
+	health := make(map[string]string)
+	for _, status := range resp.GetHealthStatus() {
+		project, _, zone, _, name := parseResourceURL(status.GetInstance())
+		key := (&Instance{Name: name, Project: project, Zone: zone}).key()
+		if health[key] != "HEALTHY" {
+			health[key] = status.GetHealthState()
+		}
 	}
 
-	urlMap, err := targetHTTPProxy.GetURLMap(projectID)
+	return health, nil
+}
+
+// applyHealth sets the health state of the instances in the backend service.
+// The state stays unknown when the health can't be checked, so the instances are still counted.
+func (b *BackendService) applyHealth(ctx context.Context, group string, instances []*Instance) {
+	health, err := b.getHealth(ctx, group)
 	if err != nil {
-		log.Printf("GetLoadBalancingHTTPS - GetURLMap: %v", err)
-		return LoadBalancingHTTPS{}, false
+		log.Printf("Failed to get the health of %s in %s: %v", path.Base(group), b.Name, err)
+		return
 	}
 
-	backendService, err := urlMap.GetBackendService(projectID)
-	if err != nil {
-		log.Printf("GetLoadBalancingHTTPS - GetBackendService: %v", err)
-		return LoadBalancingHTTPS{}, false
+	// Instances without any health status are not checked yet, e.g. just added to the group,
+	// so their health stays empty like the one which can't be checked
+	for _, instance := range instances {
+		instance.Health = health[instance.key()]
 	}
+}
 
-	instances, err := backendService.ListInstances(projectID)
-	if err != nil {
-		log.Printf("GetLoadBalancingHTTPS - backendService.ListInstances: %v", err)
-		return LoadBalancingHTTPS{}, false
+// getZoneNetworkEndpointGroupInstances resolves GCE_VM_IP_PORT endpoints into the VMs which serve them.
+// With GKE container-native load balancing each endpoint is a Pod IP and the instance is the node running the Pod.
+func getZoneNetworkEndpointGroupInstances(ctx context.Context, projectID string, neg *computepb.NetworkEndpointGroup, zone string) ([]*Instance, error) {
+	if neg.GetNetworkEndpointType() != "GCE_VM_IP_PORT" && neg.GetNetworkEndpointType() != "GCE_VM_IP" {
+		return nil, nil
 	}
 
-	serverlesses, err := backendService.ListServerlesses(projectID)
+	c, err := compute.NewNetworkEndpointGroupsRESTClient(ctx, recorder.HTTPClientOptions()...)
 	if err != nil {
-		log.Printf("GetLoadBalancingHTTPS - backendService.ListServerlesses: %v", err)
-		return LoadBalancingHTTPS{}, false
+		return nil, err
 	}
+	defer c.Close()
 
-	var backends []computing.Computing
-	for _, x := range instances {
-		b, err := x.GetComputeEngine(projectID)
+	name := neg.GetName()
+
+	req := &computepb.ListNetworkEndpointsNetworkEndpointGroupsRequest{
+		Project:              projectID,
+		NetworkEndpointGroup: name,
+		Zone:                 zone,
+		NetworkEndpointGroupsListEndpointsRequestResource: &computepb.NetworkEndpointGroupsListEndpointsRequest{},
+	}
+	it := c.ListNetworkEndpoints(ctx, req)
+	seen := make(map[string]bool)
+	var instances []*Instance
+	for {
+		resp, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
 		if err != nil {
-			log.Printf("GetLoadBalancingHTTPS - x.GetComputeEngine: %v", err)
-			return LoadBalancingHTTPS{}, false
+			return nil, err
+		}
+
+		endpoint := resp.GetNetworkEndpoint()
+		log.Printf("Network endpoint: %s:%d on %s was found in %s (%s)", endpoint.GetIpAddress(), endpoint.GetPort(), endpoint.GetInstance(), name, zone)
+		if endpoint.GetInstance() == "" || seen[endpoint.GetInstance()] {
+			continue
 		}
+		seen[endpoint.GetInstance()] = true
 
-		backends = append(backends, b)
+		instances = append(instances, &Instance{
+			Name:    path.Base(endpoint.GetInstance()),
+			Project: projectOf(endpoint.GetInstance(), projectID),
+			Zone:    zone,
+		})
 	}
 
-	for _, serverless := range serverlesses {
-		b, err := serverless.Get(projectID)
+	return instances, nil
+}
+
+// ListExternalEndpoints returns the endpoints outside of Google Cloud:
+// internet NEGs (INTERNET_FQDN_PORT, INTERNET_IP_PORT) and hybrid NEGs (NON_GCP_PRIVATE_IP_PORT)
+func (b *BackendService) ListExternalEndpoints(ctx context.Context) ([]*ExternalEndpoint, error) {
+	var endpoints []*ExternalEndpoint
+	for _, backend := range b.Backends {
+		project, locationType, location, groupType, _ := parseResourceURL(backend.GetGroup())
+		if groupType != "networkEndpointGroups" {
+			continue
+		}
+		if project == "" {
+			project = b.Project
+		}
+
+		neg, err := b.getNetworkEndpointGroup(ctx, backend.GetGroup())
 		if err != nil {
-			log.Printf("GetLoadBalancingHTTPS - serverless.Get: %v", err)
-			return LoadBalancingHTTPS{}, false
+			return nil, err
 		}
 
-		backends = append(backends, b)
+		if locationType == "global" {
+			x, err := getGlobalNetworkEndpoints(ctx, project, neg)
+			if err != nil {
+				return nil, err
+			}
+
+			endpoints = append(endpoints, x...)
+		}
+
+		if locationType == "zones" {
+			x, err := getZoneHybridNetworkEndpoints(ctx, project, neg, location)
+			if err != nil {
+				return nil, err
+			}
+
+			endpoints = append(endpoints, x...)
+		}
+
+		if locationType == "regions" {
+			endpoints = append(endpoints, getRegionInternetNetworkEndpoints(neg)...)
+		}
 	}
 
-	return LoadBalancingHTTPS{
-		id:       forwardingRule.Name,
-		backends: backends,
-	}, true
+	return endpoints, nil
 }
 
-func getForwardingRule(projectID string, hostIP string) (*ForwardingRule, error) {
-	ctx := context.Background()
-	c, err := compute.NewForwardingRulesRESTClient(ctx)
+func getGlobalNetworkEndpoints(ctx context.Context, projectID string, neg *computepb.NetworkEndpointGroup) ([]*ExternalEndpoint, error) {
+	c, err := compute.NewGlobalNetworkEndpointGroupsRESTClient(ctx, recorder.HTTPClientOptions()...)
 	if err != nil {
 		return nil, err
 	}
 	defer c.Close()
 
-	req := &computepb.AggregatedListForwardingRulesRequest{
-		Project: projectID,
+	req := &computepb.ListNetworkEndpointsGlobalNetworkEndpointGroupsRequest{
+		Project:              projectID,
+		NetworkEndpointGroup: neg.GetName(),
 	}
-	it := c.AggregatedList(ctx, req)
+	it := c.ListNetworkEndpoints(ctx, req)
+	var endpoints []*ExternalEndpoint
 	for {
 		resp, err := it.Next()
 		if err == iterator.Done {
@@ -138,126 +1192,90 @@ func getForwardingRule(projectID string, hostIP string) (*ForwardingRule, error)
 			return nil, err
 		}
 
-		for _, rule := range resp.Value.GetForwardingRules() {
-			if rule.GetIPAddress() == hostIP {
-				return &ForwardingRule{
-					Name:       rule.GetName(),
-					Region:     path.Base(rule.GetRegion()),
-					TargetPool: path.Base(rule.GetTarget()),
-				}, nil
-			}
-		}
+		endpoints = append(endpoints, newExternalEndpoint(neg, resp.GetNetworkEndpoint()))
 	}
 
-	return nil, fmt.Errorf("None forwarding rule matched to the host ipaddress")
+	return endpoints, nil
 }
 
-func (f *ForwardingRule) GetTargetHttpProxy(projectID string) (*TargetHTTPProxy, error) {
-	ctx := context.Background()
-	c, err := compute.NewTargetHttpProxiesRESTClient(ctx)
+func getZoneHybridNetworkEndpoints(ctx context.Context, projectID string, neg *computepb.NetworkEndpointGroup, zone string) ([]*ExternalEndpoint, error) {
+	if neg.GetNetworkEndpointType() != "NON_GCP_PRIVATE_IP_PORT" {
+		return nil, nil
+	}
+
+	c, err := compute.NewNetworkEndpointGroupsRESTClient(ctx, recorder.HTTPClientOptions()...)
 	if err != nil {
 		return nil, err
 	}
 	defer c.Close()
 
-	req := &computepb.GetTargetHttpProxyRequest{
-		Project:         projectID,
-		TargetHttpProxy: f.TargetPool,
+	req := &computepb.ListNetworkEndpointsNetworkEndpointGroupsRequest{
+		Project:              projectID,
+		NetworkEndpointGroup: neg.GetName(),
+		Zone:                 zone,
+		NetworkEndpointGroupsListEndpointsRequestResource: &computepb.NetworkEndpointGroupsListEndpointsRequest{},
 	}
-	resp, err := c.Get(ctx, req)
-	if err != nil {
-		return nil, err
+	it := c.ListNetworkEndpoints(ctx, req)
+	var endpoints []*ExternalEndpoint
+	for {
+		resp, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		endpoints = append(endpoints, newExternalEndpoint(neg, resp.GetNetworkEndpoint()))
 	}
 
-	return &TargetHTTPProxy{
-		Name:   resp.GetName(),
-		URLMap: path.Base(resp.GetUrlMap()),
-	}, nil
+	return endpoints, nil
 }
 
-func (t *TargetHTTPProxy) GetURLMap(projectID string) (*URLMap, error) {
-	ctx := context.Background()
-	c, err := compute.NewUrlMapsRESTClient(ctx)
-	if err != nil {
-		return nil, err
+// getRegionInternetNetworkEndpoints returns the regional internet NEG as an external endpoint.
+// The endpoints of regional NEGs can't be listed with the compute client, so the NEG stands for them.
+func getRegionInternetNetworkEndpoints(neg *computepb.NetworkEndpointGroup) []*ExternalEndpoint {
+	if !strings.HasPrefix(neg.GetNetworkEndpointType(), "INTERNET_") {
+		return nil
 	}
-	defer c.Close()
 
-	req := &computepb.GetUrlMapRequest{
-		Project: projectID,
-		UrlMap:  t.URLMap,
-	}
-	resp, err := c.Get(ctx, req)
-	if err != nil {
-		return nil, err
+	return []*ExternalEndpoint{
+		{
+			Group:    neg.GetName(),
+			Type:     neg.GetNetworkEndpointType(),
+			Address:  fmt.Sprintf("%s:%d", neg.GetName(), neg.GetDefaultPort()),
+			Internet: true,
+		},
 	}
-
-	return &URLMap{
-		Name:           resp.GetName(),
-		DefaultService: path.Base(resp.GetDefaultService()),
-	}, nil
 }
 
-func (u *URLMap) GetBackendService(projectID string) (*BackendService, error) {
-	ctx := context.Background()
-	c, err := compute.NewBackendServicesRESTClient(ctx)
-	if err != nil {
-		return nil, err
+func newExternalEndpoint(neg *computepb.NetworkEndpointGroup, endpoint *computepb.NetworkEndpoint) *ExternalEndpoint {
+	address := endpoint.GetIpAddress()
+	if endpoint.GetFqdn() != "" {
+		address = endpoint.GetFqdn()
 	}
-	defer c.Close()
 
-	req := &computepb.GetBackendServiceRequest{
-		Project:        projectID,
-		BackendService: u.DefaultService,
-	}
-	resp, err := c.Get(ctx, req)
-	if err != nil {
-		return nil, err
+	port := endpoint.GetPort()
+	if port == 0 {
+		port = neg.GetDefaultPort()
 	}
 
-	return &BackendService{
-		Name:     resp.GetName(),
-		Backends: resp.GetBackends(),
-	}, nil
+	return &ExternalEndpoint{
+		Group:    neg.GetName(),
+		Type:     neg.GetNetworkEndpointType(),
+		Address:  fmt.Sprintf("%s:%d", address, port),
+		Internet: strings.HasPrefix(neg.GetNetworkEndpointType(), "INTERNET_"),
+	}
 }
 
-func (b *BackendService) ListInstances(projectID string) ([]*Instance, error) {
-	var instances []*Instance
-	for _, backend := range b.Backends {
-		name := path.Base(backend.GetGroup())
-		locationType := strings.Split(backend.GetGroup(), "/")[7]
-		location := strings.Split(backend.GetGroup(), "/")[8]
-		groupType := strings.Split(backend.GetGroup(), "/")[9]
-
-		if groupType != "instanceGroups" {
-			continue
-		}
-
-		if locationType == "regions" {
-			x, err := getRegionInstanceGroup(projectID, name, location)
-			if err != nil {
-				return nil, err
-			}
-
-			instances = append(instances, x...)
-		}
-
-		if locationType == "zones" {
-			x, err := getZoneInstanceGroup(projectID, name, location)
-			if err != nil {
-				return nil, err
-			}
-
-			instances = append(instances, x...)
-		}
+func getRegionInstanceGroup(ctx context.Context, projectID string, name string, region string) ([]*Instance, error) {
+	// The members aren't a part of the asset data, but an empty group in the snapshot has nothing to list
+	group := &computepb.InstanceGroup{}
+	if getFromInventory(projectID, inventory.ASSET_INSTANCE_GROUP, fmt.Sprintf("projects/%s/regions/%s/instanceGroups/%s", projectID, region, name), group) && group.GetSize() == 0 {
+		return nil, nil
 	}
 
-	return instances, nil
-}
-
-func getRegionInstanceGroup(projectID string, name string, region string) ([]*Instance, error) {
-	ctx := context.Background()
-	c, err := compute.NewRegionInstanceGroupsRESTClient(ctx)
+	c, err := compute.NewRegionInstanceGroupsRESTClient(ctx, recorder.HTTPClientOptions()...)
 	if err != nil {
 		return nil, err
 	}
@@ -279,18 +1297,24 @@ func getRegionInstanceGroup(projectID string, name string, region string) ([]*In
 			return nil, err
 		}
 		instances = append(instances, &Instance{
-			Name:   path.Base(resp.GetInstance()),
-			Zone:   strings.Split(resp.GetInstance(), "/")[8],
-			Status: resp.GetStatus(),
+			Name:    path.Base(resp.GetInstance()),
+			Project: projectOf(resp.GetInstance(), projectID),
+			Zone:    strings.Split(resp.GetInstance(), "/")[8],
+			Status:  resp.GetStatus(),
 		})
 	}
 
 	return instances, nil
 }
 
-func getZoneInstanceGroup(projectID string, name string, zone string) ([]*Instance, error) {
-	ctx := context.Background()
-	c, err := compute.NewInstanceGroupsRESTClient(ctx)
+func getZoneInstanceGroup(ctx context.Context, projectID string, name string, zone string) ([]*Instance, error) {
+	// The members aren't a part of the asset data, but an empty group in the snapshot has nothing to list
+	group := &computepb.InstanceGroup{}
+	if getFromInventory(projectID, inventory.ASSET_INSTANCE_GROUP, fmt.Sprintf("projects/%s/zones/%s/instanceGroups/%s", projectID, zone, name), group) && group.GetSize() == 0 {
+		return nil, nil
+	}
+
+	c, err := compute.NewInstanceGroupsRESTClient(ctx, recorder.HTTPClientOptions()...)
 	if err != nil {
 		return nil, err
 	}
@@ -312,57 +1336,153 @@ func getZoneInstanceGroup(projectID string, name string, zone string) ([]*Instan
 			return nil, err
 		}
 		instances = append(instances, &Instance{
-			Name:   path.Base(resp.GetInstance()),
-			Zone:   strings.Split(resp.GetInstance(), "/")[8],
-			Status: resp.GetStatus(),
+			Name:    path.Base(resp.GetInstance()),
+			Project: projectOf(resp.GetInstance(), projectID),
+			Zone:    strings.Split(resp.GetInstance(), "/")[8],
+			Status:  resp.GetStatus(),
 		})
 	}
 
 	return instances, nil
 }
 
-func (b *BackendService) ListServerlesses(projectID string) ([]*Serverless, error) {
-	ctx := context.Background()
-	c, err := compute.NewRegionNetworkEndpointGroupsRESTClient(ctx)
-	if err != nil {
-		return nil, err
-	}
-	defer c.Close()
-
+func (b *BackendService) ListServerlesses(ctx context.Context) ([]*Serverless, error) {
 	var serverlesses []*Serverless
 	for _, backend := range b.Backends {
-		name := path.Base(backend.GetGroup())
-		region := strings.Split(backend.GetGroup(), "/")[8]
-		groupType := strings.Split(backend.GetGroup(), "/")[9]
+		project, locationType, region, groupType, name := parseResourceURL(backend.GetGroup())
 
-		if groupType != "networkEndpointGroups" {
+		if groupType != "networkEndpointGroups" || locationType != "regions" {
 			continue
 		}
 
-		req := &computepb.GetRegionNetworkEndpointGroupRequest{
-			Project:              projectID,
-			NetworkEndpointGroup: name,
-			Region:               region,
+		if project == "" {
+			project = b.Project
 		}
-		resp, err := c.Get(ctx, req)
+
+		resp, err := b.getNetworkEndpointGroup(ctx, backend.GetGroup())
+		if err != nil {
+			return nil, err
+		}
+
+		// Regional internet NEGs are external endpoints and Private Service Connect NEGs are out of scope
+		if resp.GetNetworkEndpointType() != "SERVERLESS" {
+			if resp.GetNetworkEndpointType() == "PRIVATE_SERVICE_CONNECT" {
+				log.Printf("Private Service Connect NEG: %s in %s is not supported and was skipped", name, region)
+			}
+			continue
+		}
+
+		x, err := newServerlesses(ctx, project, region, resp)
 		if err != nil {
 			return nil, err
 		}
+		serverlesses = append(serverlesses, x...)
+	}
+
+	return serverlesses, nil
+}
+
+// newServerlesses resolves a serverless NEG into its services.
+// A NEG with a URL mask instead of a service name can route to every service in the region,
+// so all of them are treated as backends.
+func newServerlesses(ctx context.Context, projectID string, region string, neg *computepb.NetworkEndpointGroup) ([]*Serverless, error) {
+	var serverlesses []*Serverless
+
+	if serverless := neg.GetCloudRun(); serverless != nil {
+		names := []string{serverless.GetService()}
+		if serverless.GetService() == "" && serverless.GetUrlMask() != "" {
+			x, err := cloudrun.ListServiceIDs(ctx, projectID, region)
+			if err != nil {
+				return nil, err
+			}
+			names = x
+		}
 
-		if serverless := resp.GetCloudRun(); serverless != nil {
+		for _, name := range names {
 			serverlesses = append(serverlesses, &Serverless{
-				Name:    serverless.GetService(),
+				Name:    name,
+				Project: projectID,
 				Region:  region,
 				Service: "Cloud Run",
 			})
 		}
 	}
 
+	if serverless := neg.GetAppEngine(); serverless != nil {
+		names := []string{serverless.GetService()}
+		if serverless.GetService() == "" && serverless.GetUrlMask() != "" {
+			x, err := appengine.ListServiceIDs(ctx, projectID)
+			if err != nil {
+				return nil, err
+			}
+			names = x
+		} else if serverless.GetService() == "" {
+			names = []string{"default"}
+		}
+
+		for _, name := range names {
+			serverlesses = append(serverlesses, &Serverless{
+				Name:    name,
+				Project: projectID,
+				Region:  region,
+				Service: "App Engine",
+				Version: serverless.GetVersion(),
+			})
+		}
+	}
+
+	if serverless := neg.GetCloudFunction(); serverless != nil {
+		names := []string{serverless.GetFunction()}
+		if serverless.GetFunction() == "" && serverless.GetUrlMask() != "" {
+			x, err := cloudfunctions.ListFunctionIDs(ctx, projectID, region)
+			if err != nil {
+				return nil, err
+			}
+			names = x
+		}
+
+		for _, name := range names {
+			serverlesses = append(serverlesses, &Serverless{
+				Name:    name,
+				Project: projectID,
+				Region:  region,
+				Service: "Cloud Functions",
+			})
+		}
+	}
+
+	if len(serverlesses) == 0 {
+		return nil, fmt.Errorf("Serverless NEG: %s doesn't refer to any service", neg.GetName())
+	}
+
 	return serverlesses, nil
 }
 
-func (x *Instance) GetComputeEngine(projectID string) (computeengine.ComputeEngine, error) {
-	c, err := computeengine.GetComputeInstance(projectID, x.Zone, x.Name)
+// IsServing returns true when the instance is running and passes the health checks.
+// The status of the endpoints of NEGs and the health which can't be checked are empty.
+func (x *Instance) IsServing() bool {
+	if x.Status != "" && x.Status != "RUNNING" {
+		return false
+	}
+
+	return x.Health == "" || x.Health == "HEALTHY"
+}
+
+func (x *Instance) key() string {
+	return fmt.Sprintf("instance/%s/%s/%s", x.Project, x.Zone, x.Name)
+}
+
+func (x *Serverless) key() string {
+	return fmt.Sprintf("%s/%s/%s/%s/%s", x.Service, x.Project, x.Region, x.Name, x.Version)
+}
+
+func (x *Instance) GetComputeEngine(ctx context.Context) (computeengine.ComputeEngine, error) {
+	// The cost of a managed instance is derived from its group rather than the instance itself
+	if x.Managed {
+		return computeengine.NewComputeEngine(x.Name, x.Zone, x.Cost, x.References, x.ServiceAccounts), nil
+	}
+
+	c, err := computeengine.GetComputeInstance(ctx, x.Project, x.Zone, x.Name)
 	if err != nil {
 		return computeengine.ComputeEngine{}, err
 	}
@@ -370,10 +1490,18 @@ func (x *Instance) GetComputeEngine(projectID string) (computeengine.ComputeEngi
 	return c, nil
 }
 
-func (x *Serverless) Get(projectID string) (computing.Computing, error) {
+func (x *BackendBucket) GetCloudStorage(ctx context.Context) (cloudstorage.CloudStorage, error) {
+	return cloudstorage.GetCloudStorage(ctx, x.BucketName, x.EnableCDN)
+}
+
+func (x *Serverless) Get(ctx context.Context) (computing.Computing, error) {
 	switch x.Service {
 	case "Cloud Run":
-		return cloudrun.GetCloudRunService(projectID, x.Region, x.Name)
+		return cloudrun.GetCloudRunService(ctx, x.Project, x.Region, x.Name)
+	case "App Engine":
+		return appengine.GetAppEngineService(ctx, x.Project, x.Name, x.Version)
+	case "Cloud Functions":
+		return cloudfunctions.GetCloudFunction(ctx, x.Project, x.Region, x.Name)
 	default:
 		return nil, fmt.Errorf("%s is not supported service", x.Service)
 	}
@@ -383,6 +1511,71 @@ func (r LoadBalancingHTTPS) GetID() string {
 	return r.id
 }
 
+func (r LoadBalancingHTTPS) GetRegion() string {
+	return r.region
+}
+
+func (r LoadBalancingHTTPS) GetScheme() string {
+	return r.scheme
+}
+
+func (r LoadBalancingHTTPS) GetTargetProxyName() string {
+	return r.targetProxy
+}
+
+func (r LoadBalancingHTTPS) GetURLMapName() string {
+	return r.urlMap
+}
+
+// GetRoutes returns the backend services and the backend buckets of the URL map
+func (r LoadBalancingHTTPS) GetRoutes() []Route {
+	return r.routes
+}
+
+func (r LoadBalancingHTTPS) IsRegional() bool {
+	return r.region != ""
+}
+
 func (r LoadBalancingHTTPS) GetBackends() []computing.Computing {
 	return r.backends
 }
+
+// GetProjectID returns the project owning the backend
+func (r LoadBalancingHTTPS) GetProjectID(backendID string) (string, bool) {
+	projectID, ok := r.owners[backendID]
+	return projectID, ok
+}
+
+func (r LoadBalancingHTTPS) GetBuckets() []storage.Storage {
+	return r.buckets
+}
+
+func (r LoadBalancingHTTPS) GetExternalEndpoints() []*ExternalEndpoint {
+	return r.externalEndpoints
+}
+
+func (r LoadBalancingHTTPS) IsCDNEnabled() bool {
+	return r.cdnEnabled
+}
+
+// GetUnhealthyInstances returns the instances which are stopped or fail the health checks
+func (r LoadBalancingHTTPS) GetUnhealthyInstances() []*Instance {
+	return r.unhealthyInstances
+}
+
+// IsHealthy returns false when the backend is an unhealthy instance.
+// Instances in different zones can have the same name, so the zone is compared as well.
+func (r LoadBalancingHTTPS) IsHealthy(app computing.Computing) bool {
+	for _, instance := range r.unhealthyInstances {
+		if instance.Name == app.GetID() && instance.Zone == app.GetZone() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsHTTPSEnabled returns true when the IP address of the load balancer serves HTTPS
+func (r LoadBalancingHTTPS) IsHTTPSEnabled() bool {
+	return r.httpsEnabled
+}
@@ -0,0 +1,69 @@
+package loadbalancing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mittz/roleplay-webapp-assess/architecture/inventory"
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+)
+
+func TestListForwardingRulesFallsBackToAPI(t *testing.T) {
+	defer inventory.Reset()
+	inventory.Set("p", nil)
+
+	original := listForwardingRulesFromAPI
+	defer func() { listForwardingRulesFromAPI = original }()
+	name, address, target := "just-created", "203.0.113.10", "https://www.googleapis.com/compute/v1/projects/p/global/targetHttpsProxies/proxy"
+	listForwardingRulesFromAPI = func(ctx context.Context, projectID string) ([]*computepb.ForwardingRule, error) {
+		return []*computepb.ForwardingRule{{Name: &name, IPAddress: &address, Target: &target}}, nil
+	}
+
+	rules, err := listForwardingRules(context.Background(), "p", address)
+	if err != nil {
+		t.Fatalf("listForwardingRules() error = %v", err)
+	}
+	if len(rules) != 1 || rules[0].Name != name || !rules[0].IsHTTPS() {
+		t.Errorf("listForwardingRules() = %v, want the rule from the API", rules)
+	}
+}
+
+func TestParseResourceURL(t *testing.T) {
+	tests := []struct {
+		resource                                            string
+		project, locationType, location, resourceType, name string
+	}{
+		{"https://www.googleapis.com/compute/v1/projects/service/zones/us-central1-a/networkEndpointGroups/neg", "service", "zones", "us-central1-a", "networkEndpointGroups", "neg"},
+		{"https://www.googleapis.com/compute/v1/projects/host/global/backendServices/web", "host", "global", "", "backendServices", "web"},
+		{"projects/host/regions/us-central1/instanceGroups/group", "host", "regions", "us-central1", "instanceGroups", "group"},
+		{"web", "", "", "", "", "web"},
+	}
+
+	for _, tt := range tests {
+		project, locationType, location, resourceType, name := parseResourceURL(tt.resource)
+		if project != tt.project || locationType != tt.locationType || location != tt.location || resourceType != tt.resourceType || name != tt.name {
+			t.Errorf("parseResourceURL(%q) = %q, %q, %q, %q, %q, want %q, %q, %q, %q, %q", tt.resource, project, locationType, location, resourceType, name, tt.project, tt.locationType, tt.location, tt.resourceType, tt.name)
+		}
+	}
+}
+
+func TestURLMapAddServiceKeepsProjects(t *testing.T) {
+	hostService := "https://www.googleapis.com/compute/v1/projects/host/global/backendServices/web"
+	serviceService := "https://www.googleapis.com/compute/v1/projects/service/global/backendServices/web"
+	bucket := "https://www.googleapis.com/compute/v1/projects/service/global/backendBuckets/images"
+
+	u := &URLMap{Project: "host"}
+	for _, x := range []string{hostService, serviceService, hostService, bucket, ""} {
+		u.addService(x)
+	}
+
+	if len(u.BackendServices) != 2 || u.BackendServices[0] != hostService || u.BackendServices[1] != serviceService {
+		t.Errorf("BackendServices = %v, want the services of both projects", u.BackendServices)
+	}
+	if len(u.BackendBuckets) != 1 || projectOf(u.BackendBuckets[0], u.Project) != "service" {
+		t.Errorf("BackendBuckets = %v, want the bucket of the service project", u.BackendBuckets)
+	}
+	if x := projectOf("web", u.Project); x != "host" {
+		t.Errorf("projectOf(%q) = %q, want the project of the URL map", "web", x)
+	}
+}
@@ -0,0 +1,73 @@
+package architecture
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/mittz/roleplay-webapp-assess/architecture/computing"
+	"github.com/mittz/roleplay-webapp-assess/architecture/inventory"
+	"github.com/mittz/roleplay-webapp-assess/architecture/sharedvpc"
+)
+
+// getProjectIDs returns the project and the related projects: the given ones and the Shared VPC host project.
+// The other service projects of the host project aren't searched since they usually belong to other apps.
+// The project comes first since the resources in it are preferred.
+func getProjectIDs(ctx context.Context, projectID string, relatedProjectIDs []string) []string {
+	projectIDs := []string{projectID}
+	for _, x := range relatedProjectIDs {
+		if !contains(projectIDs, x) {
+			projectIDs = append(projectIDs, x)
+		}
+	}
+
+	hostProjectID, ok, err := sharedvpc.GetHostProject(ctx, projectID)
+	if err != nil {
+		log.Printf("getProjectIDs - sharedvpc.GetHostProject: %v", err)
+	}
+	if ok && !contains(projectIDs, hostProjectID) {
+		projectIDs = append(projectIDs, hostProjectID)
+	}
+
+	return projectIDs
+}
+
+// loadInventories takes the snapshots of the projects.
+// The resolvers call the product APIs for the projects whose snapshot was not taken.
+func loadInventories(ctx context.Context, projectIDs []string) {
+	for _, projectID := range projectIDs {
+		if _, ok := inventory.Get(projectID); ok {
+			continue
+		}
+
+		if _, err := inventory.Load(ctx, projectID); err != nil {
+			log.Printf("Cloud Asset Inventory snapshot of %s was not taken, the product APIs are used instead: %v", projectID, err)
+		}
+	}
+}
+
+// getReferencedProjectIDs returns the projects of the Cloud SQL connection names the apps refer to,
+// e.g. <projectID>:<region>:<instance>
+func getReferencedProjectIDs(apps []computing.Computing) []string {
+	var projectIDs []string
+	for _, app := range apps {
+		x, ok := app.(computing.DatabaseReferrer)
+		if !ok {
+			continue
+		}
+
+		for _, reference := range x.GetDatabaseReferences() {
+			parts := strings.Split(reference, ":")
+			// The region distinguishes a connection name from the other values, e.g. 12:00:00
+			if len(parts) != 3 || parts[0] == "" || !strings.Contains(parts[1], "-") || strings.Contains(reference, "/") {
+				continue
+			}
+
+			if !contains(projectIDs, parts[0]) {
+				projectIDs = append(projectIDs, parts[0])
+			}
+		}
+	}
+
+	return projectIDs
+}
@@ -0,0 +1,56 @@
+package architecture
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Resolver looks up the A/AAAA addresses of a hostname.
+// *net.Resolver satisfies it and any other implementation can be plugged in.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+var DefaultResolver Resolver = net.DefaultResolver
+
+// normalizeEndpoint returns the hostname of the endpoint without scheme, port, path and trailing dot
+// e.g. http://shop.example.com:8080/products => shop.example.com
+func normalizeEndpoint(endpoint string) (string, error) {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = fmt.Sprintf("http://%s", endpoint)
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	hostname := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if hostname == "" {
+		return "", fmt.Errorf("Host was not found in the endpoint: %s", endpoint)
+	}
+
+	return hostname, nil
+}
+
+// resolveAddresses returns the addresses of the hostname.
+// An IP address is returned as it is.
+func resolveAddresses(ctx context.Context, resolver Resolver, hostname string) ([]string, error) {
+	if ip := net.ParseIP(hostname); ip != nil {
+		return []string{ip.String()}, nil
+	}
+
+	addresses, err := resolver.LookupHost(ctx, hostname)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("No address was resolved from %s", hostname)
+	}
+
+	return addresses, nil
+}
@@ -0,0 +1,29 @@
+package architecture
+
+import "testing"
+
+func TestNormalizeEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string
+		wantErr  bool
+	}{
+		{"shop.example.com", "shop.example.com", false},
+		{"http://shop.example.com:8080/products", "shop.example.com", false},
+		{"https://Shop.Example.com/", "shop.example.com", false},
+		{"shop.example.com.", "shop.example.com", false},
+		{"shop.example.com:443", "shop.example.com", false},
+		{"34.120.0.1", "34.120.0.1", false},
+		{"http://[2001:db8::1]:8080", "2001:db8::1", false},
+		{"", "", true},
+		{"http://:8080", "", true},
+		{"http://shop example.com", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := normalizeEndpoint(tt.endpoint)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("normalizeEndpoint(%q) = %q, %v, want %q, error %v", tt.endpoint, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
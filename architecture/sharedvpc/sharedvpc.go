@@ -0,0 +1,30 @@
+package sharedvpc
+
+import (
+	"context"
+
+	compute "cloud.google.com/go/compute/apiv1"
+	"github.com/mittz/roleplay-webapp-assess/recorder"
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+)
+
+// GetHostProject returns the Shared VPC host project which the service project is attached to
+func GetHostProject(ctx context.Context, projectID string) (string, bool, error) {
+	c, err := compute.NewProjectsRESTClient(ctx, recorder.HTTPClientOptions()...)
+	if err != nil {
+		return "", false, err
+	}
+	defer c.Close()
+
+	resp, err := c.GetXpnHost(ctx, &computepb.GetXpnHostProjectRequest{Project: projectID})
+	if err != nil {
+		return "", false, err
+	}
+
+	// The response is empty when the project isn't attached to a host project
+	if resp.GetName() == "" {
+		return "", false, nil
+	}
+
+	return resp.GetName(), true, nil
+}
@@ -0,0 +1,295 @@
+package architecture
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mittz/roleplay-webapp-assess/architecture/computing"
+	"github.com/mittz/roleplay-webapp-assess/architecture/computing/appengine"
+	"github.com/mittz/roleplay-webapp-assess/architecture/computing/cloudfunctions"
+	"github.com/mittz/roleplay-webapp-assess/architecture/computing/cloudrun"
+	"github.com/mittz/roleplay-webapp-assess/architecture/computing/computeengine"
+	"github.com/mittz/roleplay-webapp-assess/architecture/database"
+	"github.com/mittz/roleplay-webapp-assess/architecture/database/alloydb"
+	"github.com/mittz/roleplay-webapp-assess/architecture/database/bigtable"
+	"github.com/mittz/roleplay-webapp-assess/architecture/database/cloudspanner"
+	"github.com/mittz/roleplay-webapp-assess/architecture/database/cloudsql"
+	"github.com/mittz/roleplay-webapp-assess/architecture/database/firestore"
+)
+
+const (
+	NODE_ENDPOINT          = "Endpoint"
+	NODE_FORWARDING_RULE   = "Forwarding Rule"
+	NODE_TARGET_PROXY      = "Target Proxy"
+	NODE_URL_MAP           = "URL Map"
+	NODE_BACKEND           = "Backend"
+	NODE_EXTERNAL_ENDPOINT = "External Endpoint"
+	NODE_CLOUD_STORAGE     = "Cloud Storage"
+	NODE_MEMORYSTORE       = "Memorystore"
+)
+
+// Snapshot is a serialisable view of the discovered topology
+type Snapshot struct {
+	Endpoint          string         `json:"endpoint"`
+	ProjectIDs        []string       `json:"projectIds,omitempty"`
+	Resolution        []string       `json:"resolution"`
+	Nodes             []SnapshotNode `json:"nodes"`
+	Edges             []SnapshotEdge `json:"edges"`
+	AvailabilityRates []int          `json:"availabilityRates,omitempty"`
+	Availability      *Availability  `json:"expectedAvailability,omitempty"`
+	Cost              float64        `json:"cost"`
+}
+
+type SnapshotNode struct {
+	ID               string  `json:"id"`
+	Type             string  `json:"type"`
+	Name             string  `json:"name"`
+	Project          string  `json:"project,omitempty"`
+	Region           string  `json:"region,omitempty"`
+	Zone             string  `json:"zone,omitempty"`
+	Cost             float64 `json:"cost,omitempty"`
+	AvailabilityRate int     `json:"availabilityRate,omitempty"`
+	Unhealthy        bool    `json:"unhealthy,omitempty"`
+}
+
+type SnapshotEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// addNode adds the node once and returns its ID, which is unique by the type and the name
+func (s *Snapshot) addNode(node SnapshotNode) string {
+	node.ID = fmt.Sprintf("%s/%s", node.Type, node.Name)
+	for _, x := range s.Nodes {
+		if x.ID == node.ID {
+			return x.ID
+		}
+	}
+
+	s.Nodes = append(s.Nodes, node)
+	return node.ID
+}
+
+func (s *Snapshot) addEdge(from string, to string) {
+	for _, x := range s.Edges {
+		if x.From == from && x.To == to {
+			return
+		}
+	}
+
+	s.Edges = append(s.Edges, SnapshotEdge{From: from, To: to})
+}
+
+func getComputingType(app computing.Computing) string {
+	switch app.(type) {
+	case computeengine.ComputeEngine:
+		return "Compute Engine"
+	case cloudrun.CloudRun:
+		return "Cloud Run"
+	case appengine.AppEngine:
+		return "App Engine"
+	case cloudfunctions.CloudFunctions:
+		return "Cloud Functions"
+	default:
+		return NODE_BACKEND
+	}
+}
+
+func getDatabaseType(db database.Database) string {
+	switch db.(type) {
+	case cloudsql.CloudSQL:
+		return "Cloud SQL"
+	case alloydb.AlloyDB:
+		return "AlloyDB"
+	case cloudspanner.CloudSpanner:
+		return "Cloud Spanner"
+	case firestore.Firestore:
+		return "Firestore"
+	case bigtable.Bigtable:
+		return "Bigtable"
+	default:
+		return "Database"
+	}
+}
+
+func newComputingNode(app computing.Computing) SnapshotNode {
+	return SnapshotNode{
+		Type:   getComputingType(app),
+		Name:   app.GetID(),
+		Region: app.GetRegion(),
+		Zone:   app.GetZone(),
+		Cost:   app.GetCost(),
+	}
+}
+
+// GetSnapshot returns the topology from the endpoint to the database:
+// endpoint -> forwarding rule -> target proxy -> URL map -> backend services -> backends -> database
+func (a Architecture) GetSnapshot() Snapshot {
+	s := Snapshot{
+		Endpoint:   a.endpoint,
+		ProjectIDs: a.projectIDs,
+		Resolution: a.resolution,
+		Cost:       a.CalcCost(),
+	}
+	if rates, err := a.CalcAvailabilityRate(); err == nil {
+		s.AvailabilityRates = rates
+	}
+	if availability, err := a.CalcExpectedAvailability(); err == nil {
+		s.Availability = &availability
+	}
+
+	endpoint := s.addNode(SnapshotNode{Type: NODE_ENDPOINT, Name: a.endpoint})
+
+	var apps []string
+	if a.lb.GetID() != "" {
+		forwardingRule := s.addNode(SnapshotNode{Type: NODE_FORWARDING_RULE, Name: a.lb.GetID(), Region: a.lb.GetRegion()})
+		targetProxy := s.addNode(SnapshotNode{Type: NODE_TARGET_PROXY, Name: a.lb.GetTargetProxyName(), Region: a.lb.GetRegion()})
+		urlMap := s.addNode(SnapshotNode{Type: NODE_URL_MAP, Name: a.lb.GetURLMapName(), Region: a.lb.GetRegion()})
+		s.addEdge(endpoint, forwardingRule)
+		s.addEdge(forwardingRule, targetProxy)
+		s.addEdge(targetProxy, urlMap)
+
+		backends := make(map[string]computing.Computing)
+		for _, app := range a.apps {
+			backends[app.GetID()] = app
+		}
+		buckets := make(map[string]SnapshotNode)
+		for _, bucket := range a.lb.GetBuckets() {
+			buckets[bucket.GetID()] = SnapshotNode{Type: NODE_CLOUD_STORAGE, Name: bucket.GetID(), Region: bucket.GetRegion(), Cost: bucket.GetCost()}
+		}
+
+		for _, route := range a.lb.GetRoutes() {
+			x := s.addNode(SnapshotNode{Type: NODE_BACKEND, Name: route.Name, Region: a.lb.GetRegion()})
+			s.addEdge(urlMap, x)
+
+			for _, id := range route.Backends {
+				if app, ok := backends[id]; ok {
+					node := newComputingNode(app)
+					node.Unhealthy = !a.lb.IsHealthy(app)
+					y := s.addNode(node)
+					s.addEdge(x, y)
+					apps = append(apps, y)
+				}
+			}
+			for _, id := range route.Buckets {
+				if bucket, ok := buckets[id]; ok {
+					s.addEdge(x, s.addNode(bucket))
+				}
+			}
+			for _, address := range route.ExternalEndpoints {
+				s.addEdge(x, s.addNode(SnapshotNode{Type: NODE_EXTERNAL_ENDPOINT, Name: address}))
+			}
+		}
+	} else {
+		for _, app := range a.apps {
+			x := s.addNode(newComputingNode(app))
+			s.addEdge(endpoint, x)
+			apps = append(apps, x)
+		}
+	}
+
+	for _, bucket := range a.imageBuckets {
+		s.addEdge(endpoint, s.addNode(SnapshotNode{Type: NODE_CLOUD_STORAGE, Name: bucket.GetID(), Region: bucket.GetRegion(), Cost: bucket.GetCost()}))
+	}
+
+	for _, c := range a.caches {
+		x := s.addNode(SnapshotNode{Type: NODE_MEMORYSTORE, Name: c.GetID(), Region: c.GetRegion(), Cost: c.GetCost(), AvailabilityRate: c.GetAvailabilityRate()})
+		for _, app := range apps {
+			s.addEdge(app, x)
+		}
+	}
+
+	if a.db != nil {
+		x := s.addNode(SnapshotNode{Type: getDatabaseType(a.db), Name: a.db.GetID(), Cost: a.db.GetCost(), AvailabilityRate: a.db.GetAvailabilityRate()})
+		for _, app := range apps {
+			s.addEdge(app, x)
+		}
+	}
+
+	// The parts of the load balancer belong to the project of the forwarding rule
+	lbProjectID, _ := a.GetProjectID(a.lb.GetID())
+	for i, node := range s.Nodes {
+		switch node.Type {
+		case NODE_FORWARDING_RULE, NODE_TARGET_PROXY, NODE_URL_MAP, NODE_BACKEND:
+			s.Nodes[i].Project = lbProjectID
+		default:
+			s.Nodes[i].Project, _ = a.GetProjectID(node.Name)
+		}
+	}
+
+	return s
+}
+
+func (s Snapshot) ToJSON() ([]byte, error) {
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// getLabel returns the lines shown in a node of the diagrams
+func (n SnapshotNode) getLabel() []string {
+	label := []string{n.Type, n.Name}
+	if n.Zone != "" {
+		label = append(label, n.Zone)
+	} else if n.Region != "" {
+		label = append(label, n.Region)
+	}
+	if n.Cost > 0 {
+		label = append(label, fmt.Sprintf("Cost: %.2f", n.Cost))
+	}
+	if n.AvailabilityRate > 0 {
+		label = append(label, fmt.Sprintf("Availability rate: %d", n.AvailabilityRate))
+	}
+	if n.Unhealthy {
+		label = append(label, "Unhealthy")
+	}
+
+	return label
+}
+
+// getNodeIndexes returns the short identifiers of the nodes in the diagrams, e.g. n0, n1
+func (s Snapshot) getNodeIndexes() map[string]string {
+	indexes := make(map[string]string)
+	for i, node := range s.Nodes {
+		indexes[node.ID] = fmt.Sprintf("n%d", i)
+	}
+
+	return indexes
+}
+
+// ToGraphviz returns the topology in the DOT language
+func (s Snapshot) ToGraphviz() string {
+	indexes := s.getNodeIndexes()
+
+	var b strings.Builder
+	b.WriteString("digraph architecture {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+	for _, node := range s.Nodes {
+		label := strings.ReplaceAll(strings.Join(node.getLabel(), "\n"), `"`, `\"`)
+		label = strings.ReplaceAll(label, "\n", `\n`)
+		fmt.Fprintf(&b, "  %s [label=\"%s\"];\n", indexes[node.ID], label)
+	}
+	for _, edge := range s.Edges {
+		fmt.Fprintf(&b, "  %s -> %s;\n", indexes[edge.From], indexes[edge.To])
+	}
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+// ToMermaid returns the topology as a Mermaid flowchart
+func (s Snapshot) ToMermaid() string {
+	indexes := s.getNodeIndexes()
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for _, node := range s.Nodes {
+		label := strings.ReplaceAll(strings.Join(node.getLabel(), "<br/>"), `"`, "#quot;")
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", indexes[node.ID], label)
+	}
+	for _, edge := range s.Edges {
+		fmt.Fprintf(&b, "  %s --> %s\n", indexes[edge.From], indexes[edge.To])
+	}
+
+	return b.String()
+}
@@ -0,0 +1,287 @@
+package cloudstorage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mittz/roleplay-webapp-assess/cost"
+	"github.com/mittz/roleplay-webapp-assess/recorder"
+	"github.com/mittz/roleplay-webapp-assess/sla"
+	"google.golang.org/api/cloudresourcemanager/v1"
+	monitoring "google.golang.org/api/monitoring/v3"
+	"google.golang.org/api/storage/v1"
+)
+
+const (
+	LOCATION_TYPE_REGION       = "region"
+	LOCATION_TYPE_DUAL_REGION  = "dual-region"
+	LOCATION_TYPE_MULTI_REGION = "multi-region"
+	STORAGE_CLASS_STANDARD     = "STANDARD"
+	STORAGE_CLASS_NEARLINE     = "NEARLINE"
+	STORAGE_CLASS_COLDLINE     = "COLDLINE"
+	STORAGE_CLASS_ARCHIVE      = "ARCHIVE"
+	// Buckets with more objects are sized by the storage/total_bytes metric instead of listing them all
+	STORAGE_MAX_LISTED_OBJECTS = 10000
+)
+
+type CloudStorage struct {
+	id           string
+	projectID    string
+	region       string
+	locationType string
+	storageClass string
+	cdnEnabled   bool
+	cost         float64
+}
+
+type Bucket struct {
+	Name         string
+	ProjectID    string
+	Location     string
+	LocationType string
+	StorageClass string
+	SizeGiB      float64
+}
+
+func getBucket(ctx context.Context, name string) (Bucket, error) {
+	service, err := storage.NewService(ctx, recorder.HTTPClientOptions()...)
+	if err != nil {
+		return Bucket{}, err
+	}
+
+	resp, err := service.Buckets.Get(name).Context(ctx).Do()
+	if err != nil {
+		return Bucket{}, err
+	}
+
+	totalBytes, complete, err := sumObjectSizes(ctx, service, name)
+	if err != nil {
+		return Bucket{}, err
+	}
+
+	if !complete {
+		x, err := getTotalBytes(ctx, resp.ProjectNumber, name)
+		if err != nil {
+			log.Printf("The size of %s is of the first %d objects since storage/total_bytes is not available: %v", name, STORAGE_MAX_LISTED_OBJECTS, err)
+		} else {
+			totalBytes = x
+		}
+	}
+
+	// Buckets only have the project number, e.g. the ones serving the images can be in any project
+	projectID, err := getProjectID(ctx, resp.ProjectNumber)
+	if err != nil {
+		log.Printf("The project of %s (Project number: %d) was not resolved: %v", name, resp.ProjectNumber, err)
+	}
+
+	return Bucket{
+		Name:         resp.Name,
+		ProjectID:    projectID,
+		Location:     strings.ToLower(resp.Location), // US-CENTRAL1 => us-central1
+		LocationType: resp.LocationType,
+		StorageClass: resp.StorageClass,
+		SizeGiB:      float64(totalBytes) / (1024 * 1024 * 1024),
+	}, nil
+}
+
+// sumObjectSizes sums the sizes of up to STORAGE_MAX_LISTED_OBJECTS objects.
+// False is returned when the bucket has more objects than that.
+func sumObjectSizes(ctx context.Context, service *storage.Service, name string) (uint64, bool, error) {
+	var totalBytes uint64
+	listed := 0
+	pageToken := ""
+	for {
+		objects, err := service.Objects.List(name).Fields("nextPageToken", "items(size)").PageToken(pageToken).Context(ctx).Do()
+		if err != nil {
+			return 0, false, err
+		}
+
+		for _, object := range objects.Items {
+			totalBytes += object.Size
+		}
+		listed += len(objects.Items)
+
+		if objects.NextPageToken == "" {
+			return totalBytes, true, nil
+		}
+		if listed >= STORAGE_MAX_LISTED_OBJECTS {
+			return totalBytes, false, nil
+		}
+		pageToken = objects.NextPageToken
+	}
+}
+
+// getProjectID returns the ID of the project by its number
+func getProjectID(ctx context.Context, projectNumber uint64) (string, error) {
+	service, err := cloudresourcemanager.NewService(ctx, recorder.HTTPClientOptions()...)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := service.Projects.Get(fmt.Sprintf("%d", projectNumber)).Context(ctx).Do()
+	if err != nil {
+		return "", err
+	}
+
+	return resp.ProjectId, nil
+}
+
+// getTotalBytes returns the latest storage/total_bytes of the bucket, which is sampled once a day
+func getTotalBytes(ctx context.Context, projectNumber uint64, name string) (uint64, error) {
+	service, err := monitoring.NewService(ctx, recorder.HTTPClientOptions()...)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UTC()
+	resp, err := service.Projects.TimeSeries.List(fmt.Sprintf("projects/%d", projectNumber)).
+		Filter(fmt.Sprintf(`metric.type="storage.googleapis.com/storage/total_bytes" AND resource.labels.bucket_name="%s"`, name)).
+		IntervalStartTime(now.Add(-48 * time.Hour).Format(time.RFC3339)).
+		IntervalEndTime(now.Format(time.RFC3339)).
+		Context(ctx).Do()
+	if err != nil {
+		return 0, err
+	}
+
+	if len(resp.TimeSeries) == 0 {
+		return 0, fmt.Errorf("No time series of %s was found", name)
+	}
+
+	// The storage classes are separate time series and the points are in reverse time order
+	var totalBytes float64
+	for _, series := range resp.TimeSeries {
+		if len(series.Points) == 0 {
+			continue
+		}
+
+		value := series.Points[0].Value
+		if value.DoubleValue != nil {
+			totalBytes += *value.DoubleValue
+		} else if value.Int64Value != nil {
+			totalBytes += float64(*value.Int64Value)
+		}
+	}
+
+	return uint64(totalBytes), nil
+}
+
+// getStorageCostPerGiB returns the storage and retrieval prices of the bucket.
+// The colder classes are priced for a regional location.
+func getStorageCostPerGiB(bucket Bucket) (float64, float64) {
+	switch bucket.StorageClass {
+	case STORAGE_CLASS_NEARLINE:
+		return cost.STORAGE_NEARLINE_COST_PER_GIB, cost.STORAGE_NEARLINE_RETRIEVAL_COST_PER_GIB
+	case STORAGE_CLASS_COLDLINE:
+		return cost.STORAGE_COLDLINE_COST_PER_GIB, cost.STORAGE_COLDLINE_RETRIEVAL_COST_PER_GIB
+	case STORAGE_CLASS_ARCHIVE:
+		return cost.STORAGE_ARCHIVE_COST_PER_GIB, cost.STORAGE_ARCHIVE_RETRIEVAL_COST_PER_GIB
+	}
+
+	switch bucket.LocationType {
+	case LOCATION_TYPE_DUAL_REGION:
+		return cost.STORAGE_DUAL_REGION_COST_PER_GIB, 0
+	case LOCATION_TYPE_MULTI_REGION:
+		return cost.STORAGE_MULTI_REGION_COST_PER_GIB, 0
+	default:
+		return cost.STORAGE_COST_PER_GIB, 0
+	}
+}
+
+// Objects are charged for the stored volume and for the volume served to clients.
+// Cloud CDN serves cached objects at a cheaper egress rate and pulls them from the bucket once as cache fill.
+func calcCost(bucket Bucket, cdnEnabled bool) float64 {
+	storageCost, retrievalCost := getStorageCostPerGiB(bucket)
+	egressGiB := bucket.SizeGiB * cost.STORAGE_EGRESS_RATE
+	if cdnEnabled {
+		return bucket.SizeGiB*storageCost + bucket.SizeGiB*(cost.CDN_CACHE_FILL_COST_PER_GIB+retrievalCost) + egressGiB*cost.CDN_EGRESS_COST_PER_GIB
+	}
+
+	return bucket.SizeGiB*storageCost + egressGiB*(cost.STORAGE_EGRESS_COST_PER_GIB+retrievalCost)
+}
+
+// GetBucketName returns the bucket which serves the URL directly, e.g.
+// https://storage.googleapis.com/<bucket>/<object> or https://<bucket>.storage.googleapis.com/<object>
+func GetBucketName(objectURL string) (string, bool) {
+	u, err := url.Parse(objectURL)
+	if err != nil {
+		return "", false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	switch host {
+	case "storage.googleapis.com", "storage.cloud.google.com":
+		names := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 2)
+		if len(names) < 2 || names[0] == "" {
+			return "", false
+		}
+		return names[0], true
+	}
+
+	if strings.HasSuffix(host, ".storage.googleapis.com") {
+		return strings.TrimSuffix(host, ".storage.googleapis.com"), true
+	}
+
+	return "", false
+}
+
+func GetCloudStorage(ctx context.Context, bucketName string, cdnEnabled bool) (CloudStorage, error) {
+	bucket, err := getBucket(ctx, bucketName)
+	if err != nil {
+		return CloudStorage{}, fmt.Errorf("Cloud Storage bucket: %s was not found: %v", bucketName, err)
+	}
+
+	return CloudStorage{
+		id:           bucket.Name,
+		projectID:    bucket.ProjectID,
+		region:       bucket.Location,
+		locationType: bucket.LocationType,
+		storageClass: bucket.StorageClass,
+		cdnEnabled:   cdnEnabled,
+		cost:         calcCost(bucket, cdnEnabled),
+	}, nil
+}
+
+func (r CloudStorage) GetID() string {
+	return r.id
+}
+
+// GetSLA returns the availability of the location. Dual-regions and multi-regions serve the objects in a regional outage.
+func (r CloudStorage) GetSLA() float64 {
+	if r.locationType == LOCATION_TYPE_REGION {
+		return sla.CLOUD_STORAGE_REGIONAL_SLA
+	}
+
+	return sla.CLOUD_STORAGE_MULTI_REGION_SLA
+}
+
+func (r CloudStorage) GetCost() float64 {
+	return r.cost
+}
+
+func (r CloudStorage) SetCost(cost float64) {
+	r.cost = cost
+}
+
+func (r CloudStorage) GetRegion() string {
+	return r.region
+}
+
+func (r CloudStorage) GetProjectID() string {
+	return r.projectID
+}
+
+func (r CloudStorage) IsCDNEnabled() bool {
+	return r.cdnEnabled
+}
+
+func (r CloudStorage) GetLocationType() string {
+	return r.locationType
+}
+
+func (r CloudStorage) GetStorageClass() string {
+	return r.storageClass
+}
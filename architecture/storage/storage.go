@@ -0,0 +1,16 @@
+package storage
+
+type Storage interface {
+	GetID() string
+	GetCost() float64
+	SetCost(float64)
+	GetRegion() string
+	// GetSLA returns the expected availability in percent, e.g. 99.9
+	GetSLA() float64
+	// GetLocationType returns region, dual-region or multi-region
+	GetLocationType() string
+	GetStorageClass() string
+	IsCDNEnabled() bool
+	// GetProjectID returns the project owning the bucket, empty when it can't be resolved
+	GetProjectID() string
+}
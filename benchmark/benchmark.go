@@ -10,7 +10,9 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
@@ -30,6 +32,9 @@ const (
 
 var httpClient *http.Client
 
+// imageURLs keeps the image URLs served during the benchmark to find the buckets behind them
+var imageURLs sync.Map
+
 func Run(userkey, endpoint string) (int, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*BENCHMARK_TIMEOUT_SECOND)
 	eg, ctx := errgroup.WithContext(ctx)
@@ -102,6 +107,22 @@ func benchmark(ctx context.Context, endpoint string, score chan<- int) error {
 	}
 }
 
+func recordImageURL(imageURL string) {
+	imageURLs.Store(imageURL, struct{}{})
+}
+
+// GetImageURLs returns the image URLs which were seen during the benchmark
+func GetImageURLs() []string {
+	var x []string
+	imageURLs.Range(func(key, _ interface{}) bool {
+		x = append(x, key.(string))
+		return true
+	})
+	sort.Strings(x)
+
+	return x
+}
+
 func newHTTPClient() *http.Client {
 	if httpClient == nil {
 		httpClient = &http.Client{
@@ -148,6 +169,7 @@ func benchGetProducts(baseURL url.URL) int {
 	}
 
 	imagePath := imagePaths[productID]
+	recordImageURL(imagePath)
 	respImage, err := http.Get(imagePath)
 	if err != nil {
 		log.Printf("%v\n", err)
@@ -198,6 +220,7 @@ func benchPostCheckout(baseURL url.URL, productID int, productQuantity int) int
 		imagePath = fmt.Sprintf("%s://%s%s", baseURL.Scheme, baseURL.Host, imagePath)
 	}
 
+	recordImageURL(imagePath)
 	respImage, err := httpClient.Get(imagePath)
 	if err != nil {
 		log.Printf("%v\n", err)
@@ -247,6 +270,7 @@ func benchGetProduct(baseURL url.URL) int {
 		imagePath = fmt.Sprintf("%s://%s%s", baseURL.Scheme, baseURL.Host, imagePath)
 	}
 
+	recordImageURL(imagePath)
 	respImage, err := httpClient.Get(imagePath)
 	if err != nil {
 		log.Printf("%v\n", err)
@@ -297,6 +321,7 @@ func benchGetCheckouts(baseURL url.URL, productID int, productQuantity int) int
 		imagePath = fmt.Sprintf("%s://%s%s", baseURL.Scheme, baseURL.Host, imagePath)
 	}
 
+	recordImageURL(imagePath)
 	respImage, err := httpClient.Get(imagePath)
 	if err != nil {
 		log.Printf("%v\n", err)
@@ -0,0 +1,6 @@
+package cost
+
+const (
+	// A node is about 290 USD per month, so about 18 points
+	BIGTABLE_COST_PER_NODE = 290.0 / USD_PER_POINT
+)
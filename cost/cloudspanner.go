@@ -2,4 +2,7 @@ package cost
 
 const (
 	SPANNER_COST_PER_PROCESSING_UNIT = 0.4
+	// Read-only replicas are charged on top of the read-write and witness replicas of the config
+	SPANNER_READ_ONLY_REPLICA_COST_PER_PROCESSING_UNIT = 0.1
+	SPANNER_PROCESSING_UNITS_PER_NODE                  = 1000
 )
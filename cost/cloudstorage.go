@@ -0,0 +1,20 @@
+package cost
+
+const (
+	// Cloud Storage is priced in USD per GiB per month
+	STORAGE_COST_PER_GIB              = 0.02 / USD_PER_POINT
+	STORAGE_DUAL_REGION_COST_PER_GIB  = 0.044 / USD_PER_POINT
+	STORAGE_MULTI_REGION_COST_PER_GIB = 0.026 / USD_PER_POINT
+	// Colder storage classes are cheaper to store but charged for every retrieval
+	STORAGE_NEARLINE_COST_PER_GIB           = 0.01 / USD_PER_POINT
+	STORAGE_COLDLINE_COST_PER_GIB           = 0.004 / USD_PER_POINT
+	STORAGE_ARCHIVE_COST_PER_GIB            = 0.0012 / USD_PER_POINT
+	STORAGE_NEARLINE_RETRIEVAL_COST_PER_GIB = 0.01 / USD_PER_POINT
+	STORAGE_COLDLINE_RETRIEVAL_COST_PER_GIB = 0.02 / USD_PER_POINT
+	STORAGE_ARCHIVE_RETRIEVAL_COST_PER_GIB  = 0.05 / USD_PER_POINT
+	STORAGE_EGRESS_COST_PER_GIB             = 0.12 / USD_PER_POINT
+	CDN_EGRESS_COST_PER_GIB                 = 0.08 / USD_PER_POINT
+	CDN_CACHE_FILL_COST_PER_GIB             = 0.01 / USD_PER_POINT
+	// Expected egress volume against the stored volume during the competition
+	STORAGE_EGRESS_RATE = 10.0
+)
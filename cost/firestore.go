@@ -0,0 +1,20 @@
+package cost
+
+const (
+	// Firestore is charged by usage, so the cost is estimated for the monthly operations
+	// and the stored volume of the competition workload
+	FIRESTORE_EXPECTED_READS_PER_MONTH  = 400e6
+	FIRESTORE_EXPECTED_WRITES_PER_MONTH = 40e6
+	FIRESTORE_EXPECTED_STORED_GIB       = 40.0
+	// Regional prices in USD. Multi-regions cost about twice as much for both operations and storage.
+	FIRESTORE_READ_COST_PER_100K    = 0.03
+	FIRESTORE_WRITE_COST_PER_100K   = 0.09
+	FIRESTORE_STORAGE_COST_PER_GIB  = 0.1
+	FIRESTORE_MULTI_REGION_MULTIPLE = 2.0
+
+	// (120 + 36 + 4) USD = 10 points
+	FIRESTORE_REGIONAL_COST_PER_DATABASE = (FIRESTORE_EXPECTED_READS_PER_MONTH/100000*FIRESTORE_READ_COST_PER_100K +
+		FIRESTORE_EXPECTED_WRITES_PER_MONTH/100000*FIRESTORE_WRITE_COST_PER_100K +
+		FIRESTORE_EXPECTED_STORED_GIB*FIRESTORE_STORAGE_COST_PER_GIB) / USD_PER_POINT
+	FIRESTORE_MULTI_REGION_COST_PER_DATABASE = FIRESTORE_REGIONAL_COST_PER_DATABASE * FIRESTORE_MULTI_REGION_MULTIPLE
+)
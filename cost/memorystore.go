@@ -0,0 +1,8 @@
+package cost
+
+const (
+	// 1 GiB of memory at GCE_COST_PER_MEM_MIB
+	MEMORYSTORE_REDIS_COST_PER_GIB       = 2.56
+	MEMORYSTORE_MEMCACHE_COST_PER_CPU    = 1.0
+	MEMORYSTORE_MEMCACHE_COST_PER_MEM_MB = 0.0025
+)
@@ -0,0 +1,64 @@
+package cost
+
+import "fmt"
+
+const (
+	REPLICA_MODEL_MIN         = "min"
+	REPLICA_MODEL_MAX         = "max"
+	REPLICA_MODEL_AVERAGE     = "average"
+	REPLICA_MODEL_UTILIZATION = "utilization"
+	// Expected load against the capacity of the max replicas during the competition
+	REPLICA_EXPECTED_LOAD = 0.5
+)
+
+// Model of ExpectedReplicas for the autoscaled resources, which is set once at startup
+var replicaModel = REPLICA_MODEL_AVERAGE
+
+// SetReplicaModel validates and sets the model before the discovery starts,
+// so an invalid model never stops the discovery halfway
+func SetReplicaModel(model string) error {
+	switch model {
+	case REPLICA_MODEL_MIN, REPLICA_MODEL_MAX, REPLICA_MODEL_AVERAGE, REPLICA_MODEL_UTILIZATION:
+		replicaModel = model
+		return nil
+	default:
+		return fmt.Errorf("Unknown replica model: %s", model)
+	}
+}
+
+// GetReplicaModel returns the model set at startup, average by default
+func GetReplicaModel() string {
+	return replicaModel
+}
+
+// ExpectedReplicas estimates how many replicas an autoscaled resource runs on average.
+// The utilization model assumes the autoscaler keeps the utilization at the target,
+// so the lower the target, the more replicas are needed for the same load.
+func ExpectedReplicas(model string, min int, max int, targetUtilization float64) float64 {
+	if max < min {
+		max = min
+	}
+
+	switch model {
+	case REPLICA_MODEL_MIN:
+		return float64(min)
+	case REPLICA_MODEL_MAX:
+		return float64(max)
+	case REPLICA_MODEL_UTILIZATION:
+		if targetUtilization <= 0 || targetUtilization > 1 {
+			targetUtilization = 1
+		}
+
+		replicas := float64(max) * REPLICA_EXPECTED_LOAD / targetUtilization
+		if replicas < float64(min) {
+			return float64(min)
+		}
+		if replicas > float64(max) {
+			return float64(max)
+		}
+
+		return replicas
+	default:
+		return float64(min+max) / 2
+	}
+}
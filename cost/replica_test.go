@@ -0,0 +1,48 @@
+package cost
+
+import "testing"
+
+func TestSetReplicaModel(t *testing.T) {
+	defer SetReplicaModel(REPLICA_MODEL_AVERAGE)
+
+	for _, model := range []string{REPLICA_MODEL_MIN, REPLICA_MODEL_MAX, REPLICA_MODEL_AVERAGE, REPLICA_MODEL_UTILIZATION} {
+		if err := SetReplicaModel(model); err != nil || GetReplicaModel() != model {
+			t.Errorf("SetReplicaModel(%q) error = %v, model = %q", model, err, GetReplicaModel())
+		}
+	}
+
+	SetReplicaModel(REPLICA_MODEL_MAX)
+	if err := SetReplicaModel("median"); err == nil || GetReplicaModel() != REPLICA_MODEL_MAX {
+		t.Errorf("SetReplicaModel(%q) error = %v, model = %q, want an error and the model kept", "median", err, GetReplicaModel())
+	}
+}
+
+func TestExpectedReplicas(t *testing.T) {
+	tests := []struct {
+		name              string
+		model             string
+		min               int
+		max               int
+		targetUtilization float64
+		want              float64
+	}{
+		{"min", REPLICA_MODEL_MIN, 2, 10, 0.6, 2},
+		{"max", REPLICA_MODEL_MAX, 2, 10, 0.6, 10},
+		{"average", REPLICA_MODEL_AVERAGE, 2, 10, 0.6, 6},
+		{"unknown model falls back to average", "median", 1, 4, 0.6, 2.5},
+		{"utilization", REPLICA_MODEL_UTILIZATION, 1, 10, 0.8, 6.25},
+		{"utilization at full target", REPLICA_MODEL_UTILIZATION, 1, 10, 1, 5},
+		{"utilization clamped to max", REPLICA_MODEL_UTILIZATION, 1, 10, 0.25, 10},
+		{"utilization clamped to min", REPLICA_MODEL_UTILIZATION, 8, 10, 0.9, 8},
+		{"utilization without target", REPLICA_MODEL_UTILIZATION, 1, 10, 0, 5},
+		{"utilization above 1", REPLICA_MODEL_UTILIZATION, 1, 10, 1.5, 5},
+		{"max below min", REPLICA_MODEL_MAX, 3, 1, 0.6, 3},
+		{"average with max below min", REPLICA_MODEL_AVERAGE, 3, 0, 0.6, 3},
+	}
+
+	for _, tt := range tests {
+		if got := ExpectedReplicas(tt.model, tt.min, tt.max, tt.targetUtilization); got != tt.want {
+			t.Errorf("%s: ExpectedReplicas(%q, %d, %d, %v) = %v, want %v", tt.name, tt.model, tt.min, tt.max, tt.targetUtilization, got, tt.want)
+		}
+	}
+}
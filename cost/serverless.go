@@ -3,4 +3,13 @@ package cost
 const (
 	SERVERLESS_COST_PER_CPU_CORE = 0.8
 	SERVERLESS_COST_PER_MEM_MIB  = 0.002
+	// Request-only CPU allocation, the default, is costed as before
+	SERVERLESS_REQUEST_ONLY_COST_RATE = 1.0
+	// Share of the lifetime of an instance during which it serves requests
+	SERVERLESS_REQUEST_ONLY_ACTIVE_FRACTION = 0.6
+	// Always-on CPU allocation has a discounted unit price but is billed for the whole lifetime of an instance,
+	// so it costs more than request-only allocation which is billed only while serving requests
+	SERVERLESS_ALWAYS_ON_COST_RATE = 0.75 / SERVERLESS_REQUEST_ONLY_ACTIVE_FRACTION
+	// Concurrency which the expected replicas are estimated for, i.e. the default of Cloud Run
+	SERVERLESS_DEFAULT_CONCURRENCY = 80
 )
@@ -3,4 +3,8 @@ package cost
 const (
 	GCE_COST_PER_CPU_CORE = 1.0
 	GCE_COST_PER_MEM_MIB  = 0.0025
+
+	// A point is about the monthly price of a vCPU like GCE_COST_PER_CPU_CORE,
+	// so the prices of the other products in USD per month are converted to points with it
+	USD_PER_POINT = 16.0
 )
@@ -0,0 +1,99 @@
+package lint
+
+import (
+	"fmt"
+
+	"github.com/mittz/roleplay-webapp-assess/architecture/computing"
+	"github.com/mittz/roleplay-webapp-assess/architecture/database"
+	"github.com/mittz/roleplay-webapp-assess/architecture/iam"
+	"github.com/mittz/roleplay-webapp-assess/architecture/loadbalancing"
+)
+
+const (
+	SEVERITY_HIGH   = "HIGH"
+	SEVERITY_MEDIUM = "MEDIUM"
+	SEVERITY_LOW    = "LOW"
+
+	// The score is multiplied by these once per violated rule
+	SECURITY_MULTIPLIER_HIGH   = 0.8
+	SECURITY_MULTIPLIER_MEDIUM = 0.9
+	SECURITY_MULTIPLIER_LOW    = 0.95
+	// Lower bound of the security multiplier however many rules are violated
+	SECURITY_MULTIPLIER_MIN = 0.5
+)
+
+// Finding is a violation of a rule by a resource of the architecture
+type Finding struct {
+	Rule     string
+	Severity string
+	Resource string
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("[%s] %s: %s (%s)", f.Severity, f.Rule, f.Message, f.Resource)
+}
+
+// Architecture is the part of the discovered architecture which the rules check.
+// It's implemented by architecture.Architecture.
+type Architecture interface {
+	GetLoadBalancing() loadbalancing.LoadBalancingHTTPS
+	GetApps() []computing.Computing
+	GetDatabase() database.Database
+	GetIgnoredDatabases() []database.Database
+	GetProjectID(resourceID string) (string, bool)
+	GetProjectRoles(projectID string) (iam.ProjectRoles, bool)
+}
+
+type rule struct {
+	id       string
+	severity string
+	check    func(arch Architecture) []Finding
+}
+
+var rules = []rule{
+	{"database-open-to-internet", SEVERITY_HIGH, checkDatabaseOpenToInternet},
+	{"http-only-load-balancer", SEVERITY_MEDIUM, checkHTTPOnlyLoadBalancer},
+	{"cloud-run-bypasses-load-balancer", SEVERITY_MEDIUM, checkCloudRunBypassesLoadBalancer},
+	{"default-service-account-with-editor", SEVERITY_HIGH, checkDefaultServiceAccountWithEditor},
+}
+
+// Run returns the findings of every rule over the discovered architecture
+func Run(arch Architecture) []Finding {
+	var findings []Finding
+	for _, r := range rules {
+		for _, finding := range r.check(arch) {
+			finding.Rule = r.id
+			finding.Severity = r.severity
+			findings = append(findings, finding)
+		}
+	}
+
+	return findings
+}
+
+// CalcSecurityMultiplier returns the multiplier of the score.
+// A rule lowers the score once even if multiple resources violate it.
+func CalcSecurityMultiplier(findings []Finding) float64 {
+	multipliers := map[string]float64{
+		SEVERITY_HIGH:   SECURITY_MULTIPLIER_HIGH,
+		SEVERITY_MEDIUM: SECURITY_MULTIPLIER_MEDIUM,
+		SEVERITY_LOW:    SECURITY_MULTIPLIER_LOW,
+	}
+
+	multiplier := 1.0
+	violated := make(map[string]bool)
+	for _, finding := range findings {
+		if violated[finding.Rule] {
+			continue
+		}
+		violated[finding.Rule] = true
+		multiplier *= multipliers[finding.Severity]
+	}
+
+	if multiplier < SECURITY_MULTIPLIER_MIN {
+		return SECURITY_MULTIPLIER_MIN
+	}
+
+	return multiplier
+}
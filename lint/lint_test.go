@@ -0,0 +1,168 @@
+package lint
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mittz/roleplay-webapp-assess/architecture/computing"
+	"github.com/mittz/roleplay-webapp-assess/architecture/database"
+	"github.com/mittz/roleplay-webapp-assess/architecture/iam"
+	"github.com/mittz/roleplay-webapp-assess/architecture/loadbalancing"
+)
+
+type fakeArchitecture struct {
+	apps       []computing.Computing
+	db         database.Database
+	ignoredDBs []database.Database
+	owners     map[string]string
+	roles      map[string]iam.ProjectRoles
+}
+
+func (a fakeArchitecture) GetLoadBalancing() loadbalancing.LoadBalancingHTTPS {
+	return loadbalancing.LoadBalancingHTTPS{}
+}
+
+func (a fakeArchitecture) GetApps() []computing.Computing {
+	return a.apps
+}
+
+func (a fakeArchitecture) GetDatabase() database.Database {
+	return a.db
+}
+
+func (a fakeArchitecture) GetIgnoredDatabases() []database.Database {
+	return a.ignoredDBs
+}
+
+func (a fakeArchitecture) GetProjectID(resourceID string) (string, bool) {
+	projectID, ok := a.owners[resourceID]
+	return projectID, ok
+}
+
+func (a fakeArchitecture) GetProjectRoles(projectID string) (iam.ProjectRoles, bool) {
+	roles, ok := a.roles[projectID]
+	return roles, ok
+}
+
+type fakeDatabase struct {
+	id                 string
+	publicIP           bool
+	authorizedNetworks []string
+}
+
+func (d fakeDatabase) GetID() string                   { return d.id }
+func (d fakeDatabase) GetAvailabilityRate() int        { return 0 }
+func (d fakeDatabase) GetSLA() float64                 { return 0 }
+func (d fakeDatabase) GetCost() float64                { return 0 }
+func (d fakeDatabase) SetCost(float64)                 {}
+func (d fakeDatabase) GetReferences() []string         { return nil }
+func (d fakeDatabase) HasPublicIP() bool               { return d.publicIP }
+func (d fakeDatabase) GetAuthorizedNetworks() []string { return d.authorizedNetworks }
+
+type fakeApp struct {
+	id              string
+	serviceAccounts []string
+}
+
+func (a fakeApp) GetID() string                { return a.id }
+func (a fakeApp) GetCost() float64             { return 0 }
+func (a fakeApp) SetCost(float64)              {}
+func (a fakeApp) GetRegion() string            { return "" }
+func (a fakeApp) GetZone() string              { return "" }
+func (a fakeApp) GetServiceAccounts() []string { return a.serviceAccounts }
+
+func TestRun(t *testing.T) {
+	open := fakeDatabase{id: "open", publicIP: true, authorizedNetworks: []string{"203.0.113.0/24", "0.0.0.0/0"}}
+	defaultAccount := "123456789012-compute@developer.gserviceaccount.com"
+	app := fakeApp{id: "vm", serviceAccounts: []string{defaultAccount}}
+	owners := map[string]string{"vm": "p"}
+
+	tests := []struct {
+		name string
+		arch fakeArchitecture
+		want []Finding
+	}{
+		{
+			name: "nothing discovered",
+			arch: fakeArchitecture{},
+		},
+		{
+			name: "database open to the internet",
+			arch: fakeArchitecture{db: open},
+			want: []Finding{{Rule: "database-open-to-internet", Severity: SEVERITY_HIGH, Resource: "open"}},
+		},
+		{
+			name: "ignored database open to the internet",
+			arch: fakeArchitecture{db: fakeDatabase{id: "private"}, ignoredDBs: []database.Database{open}},
+			want: []Finding{{Rule: "database-open-to-internet", Severity: SEVERITY_HIGH, Resource: "open"}},
+		},
+		{
+			name: "database open to the authorized networks only",
+			arch: fakeArchitecture{db: fakeDatabase{id: "restricted", publicIP: true, authorizedNetworks: []string{"203.0.113.0/24"}}},
+		},
+		{
+			name: "database without public IP",
+			arch: fakeArchitecture{db: fakeDatabase{id: "private", authorizedNetworks: []string{"0.0.0.0/0"}}},
+		},
+		{
+			name: "default service account with editor",
+			arch: fakeArchitecture{
+				apps:   []computing.Computing{app},
+				owners: owners,
+				roles:  map[string]iam.ProjectRoles{"p": {"serviceAccount:" + defaultAccount: {"roles/viewer", iam.ROLE_EDITOR}}},
+			},
+			want: []Finding{{Rule: "default-service-account-with-editor", Severity: SEVERITY_HIGH, Resource: "vm"}},
+		},
+		{
+			name: "default service account without basic roles",
+			arch: fakeArchitecture{
+				apps:   []computing.Computing{app},
+				owners: owners,
+				roles:  map[string]iam.ProjectRoles{"p": {"serviceAccount:" + defaultAccount: {"roles/logging.logWriter"}}},
+			},
+		},
+		{
+			name: "user-managed service account with editor",
+			arch: fakeArchitecture{
+				apps:   []computing.Computing{fakeApp{id: "vm", serviceAccounts: []string{"app@p.iam.gserviceaccount.com"}}},
+				owners: owners,
+				roles:  map[string]iam.ProjectRoles{"p": {"serviceAccount:app@p.iam.gserviceaccount.com": {iam.ROLE_EDITOR}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		got := Run(tt.arch)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: Run() = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i].Rule != tt.want[i].Rule || got[i].Severity != tt.want[i].Severity || got[i].Resource != tt.want[i].Resource {
+				t.Errorf("%s: Run()[%d] = %v, want %v", tt.name, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestCalcSecurityMultiplier(t *testing.T) {
+	tests := []struct {
+		name     string
+		findings []Finding
+		want     float64
+	}{
+		{"no findings", nil, 1.0},
+		{"high", []Finding{{Rule: "a", Severity: SEVERITY_HIGH}}, SECURITY_MULTIPLIER_HIGH},
+		{"medium", []Finding{{Rule: "a", Severity: SEVERITY_MEDIUM}}, SECURITY_MULTIPLIER_MEDIUM},
+		{"low", []Finding{{Rule: "a", Severity: SEVERITY_LOW}}, SECURITY_MULTIPLIER_LOW},
+		{"same rule counted once", []Finding{{Rule: "a", Severity: SEVERITY_HIGH}, {Rule: "a", Severity: SEVERITY_HIGH}}, SECURITY_MULTIPLIER_HIGH},
+		{"rules multiply", []Finding{{Rule: "a", Severity: SEVERITY_HIGH}, {Rule: "b", Severity: SEVERITY_MEDIUM}}, SECURITY_MULTIPLIER_HIGH * SECURITY_MULTIPLIER_MEDIUM},
+		{"lower bound", []Finding{{Rule: "a", Severity: SEVERITY_HIGH}, {Rule: "b", Severity: SEVERITY_HIGH}, {Rule: "c", Severity: SEVERITY_HIGH}, {Rule: "d", Severity: SEVERITY_HIGH}}, SECURITY_MULTIPLIER_MIN},
+	}
+
+	for _, tt := range tests {
+		if got := CalcSecurityMultiplier(tt.findings); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: CalcSecurityMultiplier() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
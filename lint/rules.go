@@ -0,0 +1,113 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mittz/roleplay-webapp-assess/architecture/computing"
+	"github.com/mittz/roleplay-webapp-assess/architecture/computing/cloudrun"
+	"github.com/mittz/roleplay-webapp-assess/architecture/database"
+	"github.com/mittz/roleplay-webapp-assess/architecture/iam"
+)
+
+// The database accepts connections from any address on the internet.
+// The databases which the apps don't use are checked as well, since they are exposed all the same.
+func checkDatabaseOpenToInternet(arch Architecture) []Finding {
+	var findings []Finding
+	for _, db := range append([]database.Database{arch.GetDatabase()}, arch.GetIgnoredDatabases()...) {
+		x, ok := db.(database.PublicAccessor)
+		if !ok || !x.HasPublicIP() {
+			continue
+		}
+
+		for _, network := range x.GetAuthorizedNetworks() {
+			if network == "0.0.0.0/0" || network == "::/0" {
+				findings = append(findings, Finding{
+					Resource: db.GetID(),
+					Message:  fmt.Sprintf("The public IP address is open to %s", network),
+				})
+				break
+			}
+		}
+	}
+
+	return findings
+}
+
+// The load balancer serves only plain HTTP
+func checkHTTPOnlyLoadBalancer(arch Architecture) []Finding {
+	lb := arch.GetLoadBalancing()
+	if lb.GetID() == "" || lb.IsHTTPSEnabled() {
+		return nil
+	}
+
+	return []Finding{{
+		Resource: lb.GetID(),
+		Message:  "The load balancer doesn't have an HTTPS forwarding rule",
+	}}
+}
+
+// The Cloud Run service behind the load balancer can also be invoked directly by anyone,
+// which bypasses Cloud CDN, Cloud Armor and IAP of the load balancer
+func checkCloudRunBypassesLoadBalancer(arch Architecture) []Finding {
+	if arch.GetLoadBalancing().GetID() == "" {
+		return nil
+	}
+
+	var findings []Finding
+	for _, app := range arch.GetApps() {
+		x, ok := app.(cloudrun.CloudRun)
+		if !ok {
+			continue
+		}
+
+		access := x.GetAccess()
+		if access.Ingress == "INGRESS_TRAFFIC_ALL" && access.AllowUnauthenticated {
+			findings = append(findings, Finding{
+				Resource: x.GetID(),
+				Message:  "The service allows unauthenticated requests from all ingress, not only from the load balancer",
+			})
+		}
+	}
+
+	return findings
+}
+
+// The apps run as the Compute Engine default service account, which has the basic Editor or Owner role
+func checkDefaultServiceAccountWithEditor(arch Architecture) []Finding {
+	var findings []Finding
+	for _, app := range arch.GetApps() {
+		x, ok := app.(computing.ServiceAccountUser)
+		if !ok {
+			continue
+		}
+
+		// The roles are granted on the project owning the app
+		projectID, ok := arch.GetProjectID(app.GetID())
+		if !ok {
+			continue
+		}
+		roles, ok := arch.GetProjectRoles(projectID)
+		if !ok {
+			continue
+		}
+
+		for _, email := range x.GetServiceAccounts() {
+			if !iam.IsDefaultComputeServiceAccount(email) {
+				continue
+			}
+
+			for _, role := range roles.GetServiceAccountRoles(email) {
+				if role == iam.ROLE_EDITOR || role == iam.ROLE_OWNER {
+					findings = append(findings, Finding{
+						Resource: app.GetID(),
+						Message:  fmt.Sprintf("The default service account: %s has %s", email, strings.TrimPrefix(role, "roles/")),
+					})
+					break
+				}
+			}
+		}
+	}
+
+	return findings
+}
@@ -1,13 +1,20 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/mittz/roleplay-webapp-assess/architecture"
 	"github.com/mittz/roleplay-webapp-assess/benchmark"
+	"github.com/mittz/roleplay-webapp-assess/cost"
 	"github.com/mittz/roleplay-webapp-assess/database"
+	"github.com/mittz/roleplay-webapp-assess/lint"
+	"github.com/mittz/roleplay-webapp-assess/recorder"
 	"github.com/mittz/roleplay-webapp-assess/user"
 	"github.com/mittz/roleplay-webapp-assess/utils"
 )
@@ -16,18 +23,49 @@ func main() {
 	userkey := utils.GetEnvUserkey()
 	endpoint := utils.GetEnvEndpoint()
 	projectID := utils.GetEnvProjectID()
+
+	if err := recorder.Init(utils.GetEnvRecorderMode(), utils.GetEnvRecorderArchive()); err != nil {
+		log.Fatalf("Failed to initialize the recorder: %v", err)
+	}
+
+	if err := cost.SetReplicaModel(utils.GetEnvReplicaModel()); err != nil {
+		log.Fatalf("EXPECTED_REPLICA_MODEL is invalid: %v", err)
+	}
+	defer func() {
+		if err := recorder.Save(); err != nil {
+			log.Printf("Failed to save the recorded responses: %v", err)
+		}
+	}()
+
+	// The stored evidence is reassessed without the benchmark
+	if recorder.IsReplaying() {
+		reassess(projectID, endpoint)
+		return
+	}
+
 	jobHistory := &database.JobHistory{Userkey: userkey, LDAP: user.GetUser(userkey).LDAP, ExecutedAt: time.Now()}
 
-	arch, err := architecture.NewArchitecture(projectID, endpoint)
-	if err != nil {
+	ctx, cancel := context.WithTimeout(context.Background(), utils.GetEnvDiscoveryTimeout())
+	defer cancel()
+
+	arch, err := architecture.NewArchitectureWithResolver(ctx, projectID, utils.GetEnvRelatedProjectIDs(), endpoint, recorder.WrapResolver(architecture.DefaultResolver))
+	if errors.Is(err, architecture.ErrDiscoveryTimeout) {
+		jobHistory.Message = fmt.Sprintf("Failed to get architecture information within %s: %v", utils.GetEnvDiscoveryTimeout(), err.Error())
+		if writeErr := jobHistory.WriteDatabase(); writeErr != nil {
+			log.Println(writeErr)
+		}
+		return
+	} else if err != nil {
 		jobHistory.Message = fmt.Sprintf("Failed to get architecture information: %v", err.Error())
 		if writeErr := jobHistory.WriteDatabase(); writeErr != nil {
 			log.Println(writeErr)
 		}
 		return
 	}
+	// Exported at the end so the snapshot includes what is found after the benchmark
+	defer writeSnapshot(&arch, utils.GetEnvSnapshotDir())
 
-	availabilityRates, err := arch.CalcAvailabilityRate()
+	availabilityRates, err := calcAvailabilityRates(arch)
 	if availabilityRates == nil || len(availabilityRates) < 2 || err != nil {
 		jobHistory.AvailabilityRate = 0
 		jobHistory.Message = fmt.Sprintf("Failed to get availability rate: %v", err.Error())
@@ -38,10 +76,17 @@ func main() {
 	}
 	appRate, dbRate := availabilityRates[0], availabilityRates[1]
 	jobHistory.AvailabilityRate = utils.GetMin(appRate, dbRate)
+	cacheMessage := ""
+	if len(availabilityRates) > 2 {
+		cacheRate := availabilityRates[2]
+		jobHistory.AvailabilityRate = utils.GetMin(jobHistory.AvailabilityRate, cacheRate)
+		cacheMessage = fmt.Sprintf(" Cache rate: %d", cacheRate)
+	}
 
 	jobHistory.Cost = arch.CalcCost()
 
 	performance, err := benchmark.Run(userkey, endpoint)
+	recorder.RecordImageURLs(benchmark.GetImageURLs())
 	if err != nil {
 		jobHistory.Message = fmt.Sprintf("Failed to get benchmark score: %v", err.Error())
 		if writeErr := jobHistory.WriteDatabase(); writeErr != nil {
@@ -51,13 +96,105 @@ func main() {
 	}
 	jobHistory.Performance = performance
 
+	// Buckets serving the product images are known only after the benchmark
+	bucketCtx, bucketCancel := context.WithTimeout(context.Background(), utils.GetEnvDiscoveryTimeout())
+	defer bucketCancel()
+	arch.AddImageBuckets(bucketCtx, benchmark.GetImageURLs())
+	jobHistory.Cost = arch.CalcCost()
+
+	availabilityMessage := ""
+	if availability, err := arch.CalcExpectedAvailability(); err == nil {
+		availabilityMessage = fmt.Sprintf(" Expected availability: %.4f%%", availability.Overall)
+	}
+	if unhealthyInstances := arch.GetLoadBalancing().GetUnhealthyInstances(); len(unhealthyInstances) > 0 {
+		availabilityMessage += fmt.Sprintf(" Unhealthy backends: %d", len(unhealthyInstances))
+	}
+
 	jobHistory.Score = jobHistory.Performance * jobHistory.AvailabilityRate
+
+	findings := lint.Run(arch)
+	for _, finding := range findings {
+		log.Printf("Finding: %s", finding)
+	}
+	securityMessage := ""
+	if utils.GetEnvSecurityScoring() {
+		multiplier := lint.CalcSecurityMultiplier(findings)
+		jobHistory.Score = int(float64(jobHistory.Score) * multiplier)
+		securityMessage = fmt.Sprintf(" Security multiplier: %.2f (%d findings)", multiplier, len(findings))
+	}
+
 	jobHistory.ScoreByCost = float64(jobHistory.Score) / jobHistory.Cost
-	jobHistory.Message = fmt.Sprintf("Successfully your assessment was completed. App rate: %d DB rate: %d", appRate, dbRate)
+	jobHistory.Message = fmt.Sprintf("Successfully your assessment was completed. App rate: %d DB rate: %d%s Cloud CDN: %t%s%s", appRate, dbRate, cacheMessage, arch.IsCDNEnabled(), availabilityMessage, securityMessage)
 
 	if writeErr := jobHistory.WriteDatabase(); writeErr != nil {
 		log.Println(writeErr)
 	}
 
-	log.Printf("Successfully your assessment was completed. App rate: %d DB rate: %d", appRate, dbRate)
+	log.Printf("Successfully your assessment was completed. App rate: %d DB rate: %d%s Cloud CDN: %t%s%s", appRate, dbRate, cacheMessage, arch.IsCDNEnabled(), availabilityMessage, securityMessage)
+}
+
+// reassess rebuilds the architecture from the recorded responses and evaluates the cost and the availability again
+func reassess(projectID string, endpoint string) {
+	ctx, cancel := context.WithTimeout(context.Background(), utils.GetEnvDiscoveryTimeout())
+	defer cancel()
+
+	arch, err := architecture.NewArchitectureWithResolver(ctx, projectID, utils.GetEnvRelatedProjectIDs(), endpoint, recorder.WrapResolver(architecture.DefaultResolver))
+	if err != nil {
+		log.Printf("Failed to get architecture information: %v", err)
+		return
+	}
+	defer writeSnapshot(&arch, utils.GetEnvSnapshotDir())
+
+	arch.AddImageBuckets(ctx, recorder.GetImageURLs())
+
+	availabilityRates, err := calcAvailabilityRates(arch)
+	if err != nil {
+		log.Printf("Failed to get availability rate: %v", err)
+		return
+	}
+
+	for _, finding := range lint.Run(arch) {
+		log.Printf("Finding: %s", finding)
+	}
+
+	if availability, err := arch.CalcExpectedAvailability(); err == nil {
+		log.Printf("Expected availability: %.4f%% (Load Balancing: %.4f%%, App: %.4f%%, Storage: %.4f%%, Database: %.4f%%, Cache: %.4f%%) => Rates: %v", availability.Overall, availability.LoadBalancing, availability.App, availability.Storage, availability.Database, availability.Cache, availability.Rates)
+	}
+
+	log.Printf("Successfully the reassessment was completed. Availability rates: %v Cost: %.2f Cloud CDN: %t", availabilityRates, arch.CalcCost(), arch.IsCDNEnabled())
+}
+
+// calcAvailabilityRates returns the availability rates of the model in AVAILABILITY_MODEL
+func calcAvailabilityRates(arch architecture.Architecture) ([]int, error) {
+	if utils.GetEnvAvailabilityModel() == "composite" {
+		availability, err := arch.CalcExpectedAvailability()
+		return availability.Rates, err
+	}
+
+	return arch.CalcAvailabilityRate()
+}
+
+// writeSnapshot exports the discovered architecture as JSON, Graphviz and Mermaid
+func writeSnapshot(arch *architecture.Architecture, dir string) {
+	if dir == "" {
+		return
+	}
+
+	snapshot := arch.GetSnapshot()
+	data, err := snapshot.ToJSON()
+	if err != nil {
+		log.Printf("Failed to export the architecture: %v", err)
+		return
+	}
+
+	files := map[string][]byte{
+		"architecture.json": data,
+		"architecture.dot":  []byte(snapshot.ToGraphviz()),
+		"architecture.mmd":  []byte(snapshot.ToMermaid()),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			log.Printf("Failed to export the architecture: %v", err)
+		}
+	}
 }
@@ -0,0 +1,91 @@
+package recorder
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// getGRPCKey identifies the request by the method and the message
+func getGRPCKey(method string, req interface{}) (string, error) {
+	m, ok := req.(proto.Message)
+	if !ok {
+		return "", fmt.Errorf("Request of %s is not a protocol buffer message", method)
+	}
+
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s %s", method, hash(data)), nil
+}
+
+func recordingDialOption() grpc.DialOption {
+	return grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		key, err := getGRPCKey(method, req)
+		if err != nil {
+			return err
+		}
+
+		callErr := invoker(ctx, method, req, reply, cc, opts...)
+
+		x := GRPCResponse{}
+		if callErr != nil {
+			s := status.Convert(callErr)
+			x.Code, x.Message = uint32(s.Code()), s.Message()
+		} else if m, ok := reply.(proto.Message); ok {
+			if x.Body, err = proto.Marshal(m); err != nil {
+				return err
+			}
+		}
+
+		// The same request is answered with the latest response
+		mu.Lock()
+		archive.GRPC[key] = x
+		mu.Unlock()
+
+		return callErr
+	})
+}
+
+func replayInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	key, err := getGRPCKey(method, req)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	x, ok := archive.GRPC[key]
+	mu.Unlock()
+
+	if !ok {
+		// Unavailable would be retried by the clients
+		return status.Errorf(codes.FailedPrecondition, "Response was not recorded: %s", key)
+	}
+
+	if codes.Code(x.Code) != codes.OK {
+		return status.Error(codes.Code(x.Code), x.Message)
+	}
+
+	m, ok := reply.(proto.Message)
+	if !ok {
+		return fmt.Errorf("Response of %s is not a protocol buffer message", method)
+	}
+
+	return proto.Unmarshal(x.Body, m)
+}
+
+// newReplayConn returns a connection which never reaches the network.
+// Every call is answered by the interceptor before it's sent.
+func newReplayConn() (*grpc.ClientConn, error) {
+	return grpc.Dial("passthrough:///replay",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(replayInterceptor),
+	)
+}
@@ -0,0 +1,113 @@
+package recorder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+type recordingTransport struct {
+	base http.RoundTripper
+}
+
+type replayTransport struct{}
+
+func newRecordingHTTPClient(ctx context.Context, scope string) (*http.Client, error) {
+	// The recorder is under the authentication, so the tokens are never archived
+	transport, err := htransport.NewTransport(ctx, recordingTransport{base: http.DefaultTransport}, option.WithScopes(scope))
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// getHTTPKey identifies the request by the method, the URL with the sorted parameters and the body
+func getHTTPKey(req *http.Request) (string, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return "", err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	u := *req.URL
+	u.RawQuery = u.Query().Encode()
+	key := fmt.Sprintf("%s %s", req.Method, u.String())
+	if len(body) > 0 {
+		key = fmt.Sprintf("%s %s", key, hash(body))
+	}
+
+	return key, nil
+}
+
+func (t recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key, err := getHTTPKey(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	// The same request is answered with the latest response
+	mu.Lock()
+	archive.HTTP[key] = HTTPResponse{
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        body,
+	}
+	mu.Unlock()
+
+	return resp, nil
+}
+
+func (t replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key, err := getHTTPKey(req)
+	if err != nil {
+		return nil, err
+	}
+
+	mu.Lock()
+	x, ok := archive.HTTP[key]
+	mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("Response was not recorded: %s", key)
+	}
+
+	header := make(http.Header)
+	if x.ContentType != "" {
+		header.Set("Content-Type", x.ContentType)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", x.StatusCode, http.StatusText(x.StatusCode)),
+		StatusCode:    x.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(x.Body)),
+		ContentLength: int64(len(x.Body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,190 @@
+// Package recorder captures the cloud API responses made during the discovery into an archive
+// and replays them later, so an architecture can be reassessed after the project is torn down.
+package recorder
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+)
+
+const (
+	MODE_OFF    = ""
+	MODE_RECORD = "record"
+	MODE_REPLAY = "replay"
+	API_SCOPE   = "https://www.googleapis.com/auth/cloud-platform"
+)
+
+// Archive keeps a response per request. The requests are keyed by their content rather than their order,
+// since the discovery makes them concurrently and the order differs between the runs.
+type Archive struct {
+	HTTP      map[string]HTTPResponse `json:"http"`
+	GRPC      map[string]GRPCResponse `json:"grpc"`
+	Hosts     map[string]HostLookup   `json:"hosts"`
+	ImageURLs []string                `json:"imageURLs"`
+}
+
+type HTTPResponse struct {
+	StatusCode  int    `json:"statusCode"`
+	ContentType string `json:"contentType"`
+	Body        []byte `json:"body"`
+}
+
+type GRPCResponse struct {
+	Code    uint32 `json:"code"`
+	Message string `json:"message"`
+	Body    []byte `json:"body"`
+}
+
+type HostLookup struct {
+	Addresses []string `json:"addresses"`
+	Error     string   `json:"error"`
+}
+
+var (
+	mode    string
+	path    string
+	archive = newArchive()
+	mu      sync.Mutex
+)
+
+func newArchive() Archive {
+	return Archive{
+		HTTP:  make(map[string]HTTPResponse),
+		GRPC:  make(map[string]GRPCResponse),
+		Hosts: make(map[string]HostLookup),
+	}
+}
+
+// Init sets the mode and loads the archive to replay
+func Init(newMode string, archivePath string) error {
+	switch newMode {
+	case MODE_OFF:
+		return nil
+	case MODE_RECORD, MODE_REPLAY:
+	default:
+		return fmt.Errorf("Unknown recorder mode: %s", newMode)
+	}
+
+	if archivePath == "" {
+		return fmt.Errorf("Archive path is required in %s mode", newMode)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	mode, path, archive = newMode, archivePath, newArchive()
+	if mode == MODE_REPLAY {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &archive); err != nil {
+			return fmt.Errorf("Failed to read the archive: %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+func GetMode() string {
+	return mode
+}
+
+func IsReplaying() bool {
+	return mode == MODE_REPLAY
+}
+
+// Save writes the recorded responses into the archive
+func Save() error {
+	if mode != MODE_RECORD {
+		return nil
+	}
+
+	mu.Lock()
+	data, err := json.Marshal(archive)
+	mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// HTTPClient returns the client for REST APIs which records or replays the responses.
+// It's the client with Application Default Credentials when the recorder is off.
+func HTTPClient(ctx context.Context, scope string) (*http.Client, error) {
+	switch mode {
+	case MODE_RECORD:
+		return newRecordingHTTPClient(ctx, scope)
+	case MODE_REPLAY:
+		return &http.Client{Transport: replayTransport{}}, nil
+	default:
+		return google.DefaultClient(ctx, scope)
+	}
+}
+
+// HTTPClientOptions returns the options for the clients of REST APIs
+func HTTPClientOptions() []option.ClientOption {
+	if mode == MODE_OFF {
+		return nil
+	}
+
+	hc, err := HTTPClient(context.Background(), API_SCOPE)
+	if err != nil {
+		// The client is created without the recorder and fails in the same way, e.g. without credentials,
+		// but the responses are neither recorded nor replayed
+		log.Printf("Failed to create the HTTP client of the recorder (%s mode): %v", mode, err)
+		return nil
+	}
+
+	return []option.ClientOption{option.WithHTTPClient(hc)}
+}
+
+// GRPCClientOptions returns the options for the clients of gRPC APIs
+func GRPCClientOptions() []option.ClientOption {
+	switch mode {
+	case MODE_RECORD:
+		return []option.ClientOption{option.WithGRPCDialOption(recordingDialOption())}
+	case MODE_REPLAY:
+		conn, err := newReplayConn()
+		if err != nil {
+			log.Printf("Failed to create the gRPC connection of the recorder (%s mode): %v", mode, err)
+			return nil
+		}
+		return []option.ClientOption{option.WithGRPCConn(conn)}
+	default:
+		return nil
+	}
+}
+
+// RecordImageURLs keeps the image URLs seen during the benchmark to find the buckets in replay mode
+func RecordImageURLs(imageURLs []string) {
+	if mode != MODE_RECORD {
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	archive.ImageURLs = imageURLs
+}
+
+func GetImageURLs() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	return archive.ImageURLs
+}
+
+func hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
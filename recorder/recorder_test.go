@@ -0,0 +1,62 @@
+package recorder
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestReplayMatchesRequestsRegardlessOfOrder(t *testing.T) {
+	defer func() { mode, archive = MODE_OFF, newArchive() }()
+	mode, archive = MODE_RECORD, newArchive()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s", r.URL.Query().Get("page"), body)
+	}))
+	defer server.Close()
+
+	// The discovery makes the requests concurrently, so they are recorded in any order
+	recording := &http.Client{Transport: recordingTransport{base: http.DefaultTransport}}
+	pages := []string{"1", "2", "3"}
+	var wg sync.WaitGroup
+	for _, page := range pages {
+		page := page
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := recording.Post(fmt.Sprintf("%s/list?page=%s&size=10", server.URL, page), "text/plain", strings.NewReader("body-"+page))
+			if err != nil {
+				t.Errorf("Post() error = %v", err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	mode = MODE_REPLAY
+	replaying := &http.Client{Transport: replayTransport{}}
+	for i := len(pages) - 1; i >= 0; i-- {
+		page := pages[i]
+		// The parameters are matched regardless of their order
+		resp, err := replaying.Post(fmt.Sprintf("%s/list?size=10&page=%s", server.URL, page), "text/plain", strings.NewReader("body-"+page))
+		if err != nil {
+			t.Fatalf("Post() in replay mode error = %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if want := fmt.Sprintf("%s body-%s", page, page); string(body) != want {
+			t.Errorf("Replayed body = %q, want %q", body, want)
+		}
+	}
+
+	if _, err := replaying.Post(server.URL+"/list?page=4", "text/plain", strings.NewReader("body-4")); err == nil {
+		t.Errorf("Post() of a request which was not recorded returned no error")
+	}
+}
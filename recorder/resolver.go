@@ -0,0 +1,53 @@
+package recorder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Resolver is satisfied by the resolvers of the architecture package
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+type resolver struct {
+	base Resolver
+}
+
+// WrapResolver records or replays the DNS lookups of the endpoint in the same way as the API responses
+func WrapResolver(base Resolver) Resolver {
+	return resolver{base: base}
+}
+
+func (r resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	switch mode {
+	case MODE_RECORD:
+		addresses, err := r.base.LookupHost(ctx, host)
+		x := HostLookup{Addresses: addresses}
+		if err != nil {
+			x.Error = err.Error()
+		}
+
+		mu.Lock()
+		archive.Hosts[host] = x
+		mu.Unlock()
+
+		return addresses, err
+	case MODE_REPLAY:
+		mu.Lock()
+		x, ok := archive.Hosts[host]
+		mu.Unlock()
+
+		if !ok {
+			return nil, fmt.Errorf("Lookup of %s was not recorded", host)
+		}
+		if x.Error != "" {
+			return nil, errors.New(x.Error)
+		}
+
+		return x.Addresses, nil
+	default:
+		return r.base.LookupHost(ctx, host)
+	}
+}
@@ -0,0 +1,7 @@
+package sla
+
+// Monthly uptime percentages published in the SLA of Cloud Storage for Standard storage
+const (
+	CLOUD_STORAGE_REGIONAL_SLA     = 99.9
+	CLOUD_STORAGE_MULTI_REGION_SLA = 99.95
+)
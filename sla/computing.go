@@ -0,0 +1,16 @@
+package sla
+
+// Monthly uptime percentages published in the SLAs of the products
+const (
+	COMPUTE_ENGINE_SINGLE_ZONE_SLA = 99.9
+	COMPUTE_ENGINE_MULTI_ZONE_SLA  = 99.99
+	CLOUD_RUN_SLA                  = 99.95
+	APP_ENGINE_SLA                 = 99.95
+	CLOUD_FUNCTIONS_SLA            = 99.95
+	LOAD_BALANCING_SLA             = 99.99
+)
+
+const (
+	// Everything in a region fails with the region however many zones it uses
+	REGION_SLA = 99.99
+)
@@ -0,0 +1,19 @@
+package sla
+
+// Monthly uptime percentages published in the SLAs of the products
+const (
+	CLOUDSQL_HA_SLA = 99.95
+
+	ALLOYDB_SLA = 99.99
+
+	SPANNER_ZONAL_SLA        = 99.9
+	SPANNER_REGIONAL_SLA     = 99.99
+	SPANNER_MULTI_REGION_SLA = 99.999
+
+	FIRESTORE_REGIONAL_SLA     = 99.99
+	FIRESTORE_MULTI_REGION_SLA = 99.999
+
+	BIGTABLE_SINGLE_CLUSTER_SLA = 99.9
+	BIGTABLE_MULTI_ZONE_SLA     = 99.99
+	BIGTABLE_MULTI_REGION_SLA   = 99.999
+)
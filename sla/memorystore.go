@@ -0,0 +1,7 @@
+package sla
+
+// Monthly uptime percentages published in the SLAs of the products
+const (
+	MEMORYSTORE_REDIS_STANDARD_SLA = 99.9
+	MEMORYSTORE_MEMCACHE_SLA       = 99.9
+)
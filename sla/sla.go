@@ -0,0 +1,63 @@
+package sla
+
+const (
+	// Zonal instances of the databases and the caches aren't covered by the SLAs,
+	// so the availability of a single zone is assumed for them
+	ZONAL_ASSUMED_SLA = 99.5
+
+	// Boundaries to map an expected availability to the legacy availability rate
+	RATE_3_MIN_AVAILABILITY = 99.999
+	RATE_2_MIN_AVAILABILITY = 99.95
+	// The replicated Memorystore tiers are rated 2 in the legacy model with their SLA of 99.9
+	RATE_2_MIN_CACHE_AVAILABILITY = 99.9
+	// Series and Parallel of the SLAs aren't exact in floating point, e.g. 99.95 can be 99.94999999999999,
+	// so an availability within this margin of a boundary is rated as the boundary
+	RATE_AVAILABILITY_EPSILON = 1e-9
+)
+
+// Series returns the availability of the components which are all required, e.g. the load balancer and its backends
+func Series(availabilities ...float64) float64 {
+	x := 1.0
+	for _, availability := range availabilities {
+		x *= availability / 100
+	}
+
+	return x * 100
+}
+
+// Parallel returns the availability of the components of which any one is enough, e.g. backends in multiple regions
+func Parallel(availabilities ...float64) float64 {
+	if len(availabilities) == 0 {
+		return 0
+	}
+
+	x := 1.0
+	for _, availability := range availabilities {
+		x *= 1 - availability/100
+	}
+
+	return (1 - x) * 100
+}
+
+// ToRate maps an expected availability of the app or the database to the legacy availability rate from 1 to 3
+func ToRate(availability float64) int {
+	return toRate(availability, RATE_2_MIN_AVAILABILITY)
+}
+
+// ToCacheRate maps an expected availability of the caches to the legacy availability rate from 1 to 3
+func ToCacheRate(availability float64) int {
+	return toRate(availability, RATE_2_MIN_CACHE_AVAILABILITY)
+}
+
+func toRate(availability float64, rate2Min float64) int {
+	switch {
+	case availability+RATE_AVAILABILITY_EPSILON >= RATE_3_MIN_AVAILABILITY:
+		return 3
+	case availability+RATE_AVAILABILITY_EPSILON >= rate2Min:
+		return 2
+	case availability > 0:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,70 @@
+package sla
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSeries(t *testing.T) {
+	tests := []struct {
+		availabilities []float64
+		want           float64
+	}{
+		{nil, 100},
+		{[]float64{99.99}, 99.99},
+		{[]float64{99.99, 99.95}, 99.940005},
+		{[]float64{99.9, 99.9, 100}, 99.8001},
+	}
+
+	for _, tt := range tests {
+		if got := Series(tt.availabilities...); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Series(%v) = %v, want %v", tt.availabilities, got, tt.want)
+		}
+	}
+}
+
+func TestParallel(t *testing.T) {
+	tests := []struct {
+		availabilities []float64
+		want           float64
+	}{
+		{nil, 0},
+		{[]float64{99.5}, 99.5},
+		{[]float64{99.5, 99.5}, 99.9975},
+		{[]float64{99, 99.9}, 99.999},
+	}
+
+	for _, tt := range tests {
+		if got := Parallel(tt.availabilities...); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Parallel(%v) = %v, want %v", tt.availabilities, got, tt.want)
+		}
+	}
+}
+
+func TestToRate(t *testing.T) {
+	tests := []struct {
+		name         string
+		availability float64
+		want         int
+		wantCache    int
+	}{
+		{"unavailable", 0, 0, 0},
+		{"zonal", ZONAL_ASSUMED_SLA, 1, 1},
+		{"cache boundary", RATE_2_MIN_CACHE_AVAILABILITY, 1, 2},
+		{"rate 2 boundary", RATE_2_MIN_AVAILABILITY, 2, 2},
+		{"below rate 2 boundary", RATE_2_MIN_AVAILABILITY - 0.001, 1, 2},
+		{"rate 2 boundary in parallel", Parallel(95, 99), 2, 2},
+		{"rate 3 boundary", RATE_3_MIN_AVAILABILITY, 3, 3},
+		{"rate 3 boundary in parallel", Parallel(99, 99.9), 3, 3},
+		{"below rate 3 boundary", RATE_3_MIN_AVAILABILITY - 0.0001, 2, 2},
+	}
+
+	for _, tt := range tests {
+		if got := ToRate(tt.availability); got != tt.want {
+			t.Errorf("%s: ToRate(%v) = %d, want %d", tt.name, tt.availability, got, tt.want)
+		}
+		if got := ToCacheRate(tt.availability); got != tt.wantCache {
+			t.Errorf("%s: ToCacheRate(%v) = %d, want %d", tt.name, tt.availability, got, tt.wantCache)
+		}
+	}
+}
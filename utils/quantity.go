@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	BYTES_PER_MIB = 1024 * 1024
+)
+
+// ParseCPU parses a Kubernetes-style CPU quantity into cores, e.g. "500m" => 0.5, "1.5" => 1.5
+func ParseCPU(value string) (float64, error) {
+	q, err := resource.ParseQuantity(strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("Invalid CPU quantity: %s: %w", value, err)
+	}
+
+	return CPUFromQuantity(q), nil
+}
+
+// ParseMemoryMiB parses a Kubernetes-style memory quantity with SI or binary units into MiB,
+// e.g. "512Mi" => 512, "1G" => 953.67, "2Gi" => 2048
+func ParseMemoryMiB(value string) (float64, error) {
+	q, err := resource.ParseQuantity(strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("Invalid memory quantity: %s: %w", value, err)
+	}
+
+	return MemoryMiBFromQuantity(q), nil
+}
+
+func CPUFromQuantity(q resource.Quantity) float64 {
+	return float64(q.MilliValue()) / 1000
+}
+
+func MemoryMiBFromQuantity(q resource.Quantity) float64 {
+	return float64(q.Value()) / BYTES_PER_MIB
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseCPU(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{value: "1", want: 1},
+		{value: "2", want: 2},
+		{value: "0.5", want: 0.5},
+		{value: "1.5", want: 1.5},
+		{value: "1000m", want: 1},
+		{value: "500m", want: 0.5},
+		{value: "83m", want: 0.083},
+		{value: " 2 ", want: 2},
+		{value: "", wantErr: true},
+		{value: "one", wantErr: true},
+		{value: "1 core", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseCPU(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseCPU(%q) error = %v, wantErr %t", tt.value, err, tt.wantErr)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("ParseCPU(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseMemoryMiB(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{value: "512Mi", want: 512},
+		{value: "2Gi", want: 2048},
+		{value: "1Ki", want: 1.0 / 1024},
+		// SI units are powers of 1000
+		{value: "512M", want: 512 * 1000 * 1000 / float64(BYTES_PER_MIB)},
+		{value: "1G", want: 1000 * 1000 * 1000 / float64(BYTES_PER_MIB)},
+		// Values without a suffix are bytes
+		{value: "1048576", want: 1},
+		{value: "536870912", want: 512},
+		{value: "", wantErr: true},
+		{value: "512MB", wantErr: true},
+		{value: "abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMemoryMiB(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseMemoryMiB(%q) error = %v, wantErr %t", tt.value, err, tt.wantErr)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("ParseMemoryMiB(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -3,7 +3,11 @@ package utils
 import (
 	"log"
 	"os"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/mittz/roleplay-webapp-assess/cost"
 )
 
 func getEnv(key string) string {
@@ -15,6 +19,15 @@ func getEnv(key string) string {
 	return value
 }
 
+func getEnvOrDefault(key string, defaultValue string) string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return defaultValue
+	}
+
+	return value
+}
+
 func GetEnvUserkey() string {
 	return getEnv("USER_KEY")
 }
@@ -27,6 +40,64 @@ func GetEnvProjectID() string {
 	return getEnv("PROJECT_ID")
 }
 
+// legacy or composite. The composite model maps the expected availability from the SLAs to the rates.
+func GetEnvAvailabilityModel() string {
+	return getEnvOrDefault("AVAILABILITY_MODEL", "legacy")
+}
+
+// Projects which hold a part of the architecture, e.g. a data project. Comma separated.
+func GetEnvRelatedProjectIDs() []string {
+	var projectIDs []string
+	for _, x := range strings.Split(getEnvOrDefault("RELATED_PROJECT_IDS", ""), ",") {
+		if x = strings.TrimSpace(x); x != "" {
+			projectIDs = append(projectIDs, x)
+		}
+	}
+
+	return projectIDs
+}
+
+// min, max, average or utilization
+func GetEnvReplicaModel() string {
+	return getEnvOrDefault("EXPECTED_REPLICA_MODEL", cost.REPLICA_MODEL_AVERAGE)
+}
+
+// Directory to export the discovered architecture to. Nothing is exported when it's empty.
+func GetEnvSnapshotDir() string {
+	return getEnvOrDefault("SNAPSHOT_DIR", "")
+}
+
+// record or replay the cloud API responses. The recorder is off when it's empty.
+func GetEnvRecorderMode() string {
+	return getEnvOrDefault("RECORDER_MODE", "")
+}
+
+func GetEnvRecorderArchive() string {
+	return getEnvOrDefault("RECORDER_ARCHIVE", "")
+}
+
+// Deadline of the discovery of the architecture, e.g. 5m
+func GetEnvDiscoveryTimeout() time.Duration {
+	value := getEnvOrDefault("DISCOVERY_TIMEOUT", "5m")
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("DISCOVERY_TIMEOUT is invalid: %s", value)
+	}
+
+	return timeout
+}
+
+// Whether the lint findings lower the score. They are logged either way.
+func GetEnvSecurityScoring() bool {
+	value := getEnvOrDefault("SECURITY_SCORING", "false")
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("SECURITY_SCORING is invalid: %s", value)
+	}
+
+	return enabled
+}
+
 func GetMin(x, y int) int {
 	if x < y {
 		return x